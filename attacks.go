@@ -0,0 +1,106 @@
+package main
+
+import (
+	"github.com/notnil/chess"
+)
+
+// boardState é uma representação do tabuleiro em bitboards, mais conveniente
+// que o SquareMap para calcular quais casas cada peça ataca
+type boardState struct {
+	pieces   [64]chess.Piece
+	occupied uint64
+	byColor  [2]uint64
+	kings    [2]chess.Square
+}
+
+// newBoardState converte o SquareMap de um tabuleiro em bitboards
+func newBoardState(sm map[chess.Square]chess.Piece) *boardState {
+	bs := &boardState{kings: [2]chess.Square{chess.NoSquare, chess.NoSquare}}
+	for sq, piece := range sm {
+		bs.pieces[sq] = piece
+		bit := uint64(1) << uint(sq)
+		bs.occupied |= bit
+		bs.byColor[colorIndex(piece.Color())] |= bit
+		if piece.Type() == chess.King {
+			bs.kings[colorIndex(piece.Color())] = sq
+		}
+	}
+	return bs
+}
+
+// Deslocamentos, em colunas e fileiras, usados para gerar os ataques
+var (
+	knightOffsets = [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingOffsets   = [8][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	bishopDirs    = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	rookDirs      = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+)
+
+// Ataques do cavalo e do rei pré-calculados para cada casa
+var (
+	knightAttackTable = stepAttackTable(knightOffsets)
+	kingAttackTable   = stepAttackTable(kingOffsets)
+)
+
+// stepAttackTable calcula, para cada casa, as casas alcançadas com um único
+// passo em cada um dos deslocamentos informados
+func stepAttackTable(offsets [8][2]int) [64]uint64 {
+	var table [64]uint64
+	for sq := 0; sq < 64; sq++ {
+		for _, o := range offsets {
+			file, rank := sq%8+o[0], sq/8+o[1]
+			if file >= 0 && file < 8 && rank >= 0 && rank < 8 {
+				table[sq] |= 1 << uint(rank*8+file)
+			}
+		}
+	}
+	return table
+}
+
+// slidingAttacks calcula as casas atacadas por uma peça que desliza nas
+// direções informadas até encontrar a borda do tabuleiro ou outra peça
+func slidingAttacks(sq chess.Square, occupied uint64, dirs [4][2]int) uint64 {
+	var attacks uint64
+	for _, d := range dirs {
+		file, rank := int(sq)%8+d[0], int(sq)/8+d[1]
+		for file >= 0 && file < 8 && rank >= 0 && rank < 8 {
+			bit := uint64(1) << uint(rank*8+file)
+			attacks |= bit
+			if occupied&bit != 0 {
+				break
+			}
+			file, rank = file+d[0], rank+d[1]
+		}
+	}
+	return attacks
+}
+
+// attacksFrom calcula as casas atacadas pela peça que está na casa informada,
+// incluindo as casas ocupadas por peças de qualquer cor
+func (bs *boardState) attacksFrom(sq chess.Square) uint64 {
+	piece := bs.pieces[sq]
+	switch piece.Type() {
+	case chess.Knight:
+		return knightAttackTable[sq]
+	case chess.King:
+		return kingAttackTable[sq]
+	case chess.Bishop:
+		return slidingAttacks(sq, bs.occupied, bishopDirs)
+	case chess.Rook:
+		return slidingAttacks(sq, bs.occupied, rookDirs)
+	case chess.Queen:
+		return slidingAttacks(sq, bs.occupied, bishopDirs) | slidingAttacks(sq, bs.occupied, rookDirs)
+	case chess.Pawn:
+		file, rank := int(sq)%8, int(sq)/8
+		if piece.Color() == chess.White {
+			rank++
+		} else {
+			rank--
+		}
+		if rank < 0 || rank > 7 {
+			return 0
+		}
+		return adjacentFilesMask(file) & rankMask(rank)
+	}
+	return 0
+}
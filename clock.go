@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/spf13/viper"
+)
+
+// DelayKind é a forma como o atraso de cada jogada é descontado do relógio
+type DelayKind int
+
+const (
+	// NoDelay é o controle de tempo sem atraso
+	NoDelay DelayKind = iota
+	// SimpleDelay é o atraso simples, em que o relógio só começa a correr
+	// depois que o atraso passa
+	SimpleDelay
+	// BronsteinDelay é o atraso Bronstein, em que o relógio corre desde o
+	// início e o tempo gasto, até o valor do atraso, é devolvido após a
+	// jogada
+	BronsteinDelay
+)
+
+// TimeControl é o controle de tempo de uma partida, no formato de --tc:
+// "[jogadas/]minutos[+incremento|dAtraso|bAtraso]", como "5+3" (5 minutos com
+// 3 segundos de incremento Fischer), "40/90" (90 minutos a cada 40 jogadas),
+// "5d3" (atraso simples de 3 segundos) e "5b3" (atraso Bronstein)
+type TimeControl struct {
+	// Moves é o número de jogadas de cada sessão, ao fim da qual o tempo Base
+	// é somado ao relógio. Zero significa que o tempo vale para a partida
+	// toda
+	Moves int
+	// Base é o tempo de cada lado no início da partida e de cada sessão
+	Base time.Duration
+	// Increment é o incremento Fischer somado ao relógio após cada jogada
+	Increment time.Duration
+	// Delay é o atraso de cada jogada, descontado conforme o DelayKind
+	Delay     time.Duration
+	DelayKind DelayKind
+}
+
+// ParseTimeControl interpreta o controle de tempo no formato de --tc
+func ParseTimeControl(s string) (TimeControl, error) {
+	var tc TimeControl
+	rest := strings.TrimSpace(s)
+	if moves, base, ok := strings.Cut(rest, "/"); ok {
+		n, err := strconv.Atoi(moves)
+		if err != nil || n <= 0 {
+			return tc, fmt.Errorf("invalid time control %q, the number of moves should be a positive integer", s)
+		}
+		tc.Moves, rest = n, base
+	}
+	if i := strings.IndexAny(rest, "+db"); i >= 0 {
+		extra, ok := parseClockTime(rest[i+1:], time.Second)
+		if !ok {
+			return tc, fmt.Errorf("invalid time control %q, the increment or delay should be a number of seconds", s)
+		}
+		switch rest[i] {
+		case '+':
+			tc.Increment = extra
+		case 'd':
+			tc.Delay, tc.DelayKind = extra, SimpleDelay
+		case 'b':
+			tc.Delay, tc.DelayKind = extra, BronsteinDelay
+		}
+		rest = rest[:i]
+	}
+	// O tempo base é informado em minutos, ou em minutos e segundos no
+	// formato "m:ss"
+	minutes, seconds, hasSeconds := strings.Cut(rest, ":")
+	base, ok := parseClockTime(minutes, time.Minute)
+	if ok && hasSeconds {
+		sec, err := strconv.Atoi(seconds)
+		ok = err == nil && sec >= 0 && sec < 60
+		base += time.Duration(sec) * time.Second
+	}
+	if !ok || base <= 0 || base > maxClockTime {
+		return tc, fmt.Errorf("invalid time control %q, it should be like '5+3', '40/90', '5d3' or '2:30'", s)
+	}
+	tc.Base = base
+	return tc, nil
+}
+
+// maxClockTime é o maior tempo aceito em um controle de tempo, que evita
+// durações que não cabem em um time.Duration
+const maxClockTime = 24 * time.Hour
+
+// parseClockTime converte um número, na unidade informada, em uma duração,
+// retornando false quando ele não for um número finito entre zero e
+// maxClockTime
+func parseClockTime(s string, unit time.Duration) (time.Duration, bool) {
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(value) || value < 0 || value*float64(unit) > float64(maxClockTime) {
+		return 0, false
+	}
+	return time.Duration(value * float64(unit)), true
+}
+
+// TimeControlFlag retorna o controle de tempo informado por --tc, ou nil
+// quando as partidas não têm relógio
+func TimeControlFlag() (*TimeControl, error) {
+	s := viper.GetString(TC)
+	if s == "" {
+		return nil, nil
+	}
+	tc, err := ParseTimeControl(s)
+	if err != nil {
+		return nil, err
+	}
+	return &tc, nil
+}
+
+// String retorna o controle de tempo no formato da tag TimeControl do PGN,
+// com os tempos em segundos. O PGN não prevê atrasos, então eles são
+// escritos com o mesmo sufixo de --tc
+func (tc TimeControl) String() string {
+	s := formatSeconds(tc.Base)
+	if tc.Moves > 0 {
+		s = fmt.Sprintf("%d/%s", tc.Moves, s)
+	}
+	switch {
+	case tc.DelayKind == SimpleDelay:
+		s += "d" + formatSeconds(tc.Delay)
+	case tc.DelayKind == BronsteinDelay:
+		s += "b" + formatSeconds(tc.Delay)
+	case tc.Increment > 0:
+		s += "+" + formatSeconds(tc.Increment)
+	}
+	return s
+}
+
+// formatSeconds exibe uma duração em segundos, sem casas decimais quando
+// ela for um número inteiro de segundos
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// Clock é o relógio de xadrez com o tempo de cada lado. Somente o relógio
+// do lado que está jogando corre. O relógio pode ser consultado por outras
+// goroutines, como a que vigia a queda da bandeira
+type Clock struct {
+	TimeControl TimeControl
+
+	mu        sync.Mutex
+	remaining [2]time.Duration
+	// moves é o número de jogadas feitas por cada lado, usado para saber
+	// quando uma sessão termina
+	moves [2]int
+	turn  chess.Color
+	// moving indica que há uma jogada em andamento, em que spent é o tempo
+	// gasto até a última pausa e started é quando o relógio voltou a correr,
+	// zero enquanto ele estiver pausado
+	moving  bool
+	spent   time.Duration
+	started time.Time
+}
+
+// clockPollInterval é o intervalo em que a queda da bandeira é verificada
+// enquanto o relógio está pausado
+const clockPollInterval = 100 * time.Millisecond
+
+// NewClock cria um relógio com o tempo inicial do controle de tempo
+func NewClock(tc TimeControl) *Clock {
+	return &Clock{TimeControl: tc, remaining: [2]time.Duration{tc.Base, tc.Base}}
+}
+
+// Start começa a contar o tempo da jogada do lado informado
+func (c *Clock) Start(color chess.Color) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.turn = color
+	c.moving = true
+	c.spent = 0
+	c.started = time.Now()
+}
+
+// Pause para o relógio que está correndo sem concluir a jogada, como
+// enquanto o humano vê uma dica, até que Resume seja chamado
+func (c *Clock) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.moving && !c.started.IsZero() {
+		c.spent += time.Since(c.started)
+		c.started = time.Time{}
+	}
+}
+
+// Resume volta a correr o relógio pausado por Pause
+func (c *Clock) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.moving && c.started.IsZero() {
+		c.started = time.Now()
+	}
+}
+
+// running verifica se o relógio do lado informado está correndo
+func (c *Clock) running(color chess.Color) bool {
+	return c.moving && c.turn == color && !c.started.IsZero()
+}
+
+// elapsed retorna o tempo gasto na jogada em andamento, sem as pausas
+func (c *Clock) elapsed() time.Duration {
+	elapsed := c.spent
+	if !c.started.IsZero() {
+		elapsed += time.Since(c.started)
+	}
+	return elapsed
+}
+
+// charge retorna quanto do tempo gasto em uma jogada é descontado do
+// relógio antes de devolver o atraso Bronstein
+func (c *Clock) charge(elapsed time.Duration) time.Duration {
+	if c.TimeControl.DelayKind == SimpleDelay {
+		elapsed -= c.TimeControl.Delay
+		if elapsed < 0 {
+			elapsed = 0
+		}
+	}
+	return elapsed
+}
+
+// Remaining retorna o tempo restante do lado informado
+func (c *Clock) Remaining(color chess.Color) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remainingOf(color)
+}
+
+// remainingOf retorna o tempo restante do lado informado, descontando o
+// tempo gasto na jogada em andamento
+func (c *Clock) remainingOf(color chess.Color) time.Duration {
+	remaining := c.remaining[colorIndex(color)]
+	if c.moving && c.turn == color {
+		remaining -= c.charge(c.elapsed())
+	}
+	return remaining
+}
+
+// Watch cancela a jogada em andamento, através de cancel, quando o tempo do
+// lado que está jogando acaba. A espera acompanha as pausas do relógio e
+// termina quando o contexto informado for cancelado
+func (c *Clock) Watch(ctx context.Context, cancel context.CancelFunc) {
+	for {
+		c.mu.Lock()
+		wait := clockPollInterval
+		if c.running(c.turn) {
+			// O tempo restante nunca é maior que o tempo até a queda da
+			// bandeira, mesmo durante o atraso simples
+			wait = c.remainingOf(c.turn)
+			if wait <= 0 {
+				c.mu.Unlock()
+				cancel()
+				return
+			}
+		}
+		c.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Stop para o relógio do lado que está jogando, descontando o tempo gasto, e
+// retorna false se o tempo acabou. Quando moved é verdadeiro a jogada foi
+// concluída, então o incremento, o atraso Bronstein e o tempo de uma nova
+// sessão são somados ao relógio
+func (c *Clock) Stop(moved bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.moving {
+		return true
+	}
+	elapsed := c.elapsed()
+	c.moving = false
+	c.spent = 0
+	c.started = time.Time{}
+	i := colorIndex(c.turn)
+	c.remaining[i] -= c.charge(elapsed)
+	if c.remaining[i] <= 0 {
+		c.remaining[i] = 0
+		return false
+	}
+	if !moved {
+		return true
+	}
+	tc := c.TimeControl
+	if tc.DelayKind == BronsteinDelay {
+		if elapsed > tc.Delay {
+			elapsed = tc.Delay
+		}
+		c.remaining[i] += elapsed
+	}
+	c.remaining[i] += tc.Increment
+	c.moves[i]++
+	if tc.Moves > 0 && c.moves[i]%tc.Moves == 0 {
+		c.remaining[i] += tc.Base
+	}
+	return true
+}
+
+// MoveBudget decide quanto tempo o lado informado pode gastar na jogada
+func (c *Clock) MoveBudget(color chess.Color) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tc := c.TimeControl
+	movesToGo := 0
+	if tc.Moves > 0 {
+		movesToGo = tc.Moves - c.moves[colorIndex(color)]%tc.Moves
+	}
+	remaining := c.remainingOf(color)
+	switch tc.DelayKind {
+	case SimpleDelay:
+		// O atraso simples passa sem descontar do relógio, então ele é todo
+		// somado ao tempo da jogada
+		return tc.Delay + AllocateMoveTime(remaining, tc.Increment, movesToGo)
+	case BronsteinDelay:
+		// O atraso Bronstein é devolvido depois da jogada, como um incremento,
+		// mas precisa estar disponível no relógio durante ela
+		return AllocateMoveTime(remaining, tc.Increment+tc.Delay, movesToGo)
+	}
+	return AllocateMoveTime(remaining, tc.Increment, movesToGo)
+}
+
+// String exibe o tempo restante dos dois lados, marcando o que está correndo
+func (c *Clock) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	format := func(color chess.Color) string {
+		s := fmt.Sprintf("%s %s", color.Name(), FormatClockTime(c.remainingOf(color)))
+		if c.running(color) {
+			s += " (running)"
+		}
+		return s
+	}
+	return fmt.Sprintf("Clock: %s, %s", format(chess.White), format(chess.Black))
+}
+
+// FormatClockTime exibe o tempo de um relógio como "m:ss", ou "h:mm:ss",
+// com os décimos de segundo quando resta menos de um minuto
+func FormatClockTime(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	h, m, s := int(d/time.Hour), int(d/time.Minute)%60, int(d/time.Second)%60
+	switch {
+	case h > 0:
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	case d < time.Minute:
+		return fmt.Sprintf("%d:%02d.%d", m, s, int(d/(100*time.Millisecond))%10)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// TimedPlayer é implementado pelos jogadores que decidem quanto tempo gastar
+// em cada jogada a partir do relógio da partida
+type TimedPlayer interface {
+	UseClock(clock *Clock)
+}
+
+// timeForfeit é o valor da tag Termination de uma partida terminada pela
+// queda da bandeira
+const timeForfeit = "time forfeit"
+
+// LoseOnTime encerra a partida pela queda da bandeira do lado informado. A
+// partida termina empatada quando o adversário não tem material para dar
+// mate. O chess.Game não tem uma forma de término para a queda da bandeira,
+// então ela é registrada na tag Termination
+func LoseOnTime(game *chess.Game, color chess.Color) {
+	if hasMatingMaterial(game.Position().Board(), color.Other()) {
+		game.Resign(color)
+	} else {
+		game.Draw(chess.DrawOffer)
+	}
+	game.AddTagPair("Termination", timeForfeit)
+}
+
+// endedOnTime verifica se a partida terminou pela queda da bandeira
+func endedOnTime(game *chess.Game) bool {
+	tag := game.GetTagPair("Termination")
+	return tag != nil && tag.Value == timeForfeit && game.Outcome() != chess.NoOutcome
+}
+
+// hasMatingMaterial verifica se o lado informado tem, além do rei, peões,
+// torres, damas ou ao menos duas peças menores
+func hasMatingMaterial(board *chess.Board, color chess.Color) bool {
+	minors := 0
+	for _, piece := range board.SquareMap() {
+		if piece.Color() != color {
+			continue
+		}
+		switch piece.Type() {
+		case chess.Pawn, chess.Rook, chess.Queen:
+			return true
+		case chess.Bishop, chess.Knight:
+			minors++
+		}
+	}
+	return minors >= 2
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		s    string
+		want TimeControl
+	}{
+		{"5", TimeControl{Base: 5 * time.Minute}},
+		{"5+3", TimeControl{Base: 5 * time.Minute, Increment: 3 * time.Second}},
+		{"0:10+0.1", TimeControl{Base: 10 * time.Second, Increment: 100 * time.Millisecond}},
+		{"40/90", TimeControl{Moves: 40, Base: 90 * time.Minute}},
+		{"40/90+30", TimeControl{Moves: 40, Base: 90 * time.Minute, Increment: 30 * time.Second}},
+		{"5d3", TimeControl{Base: 5 * time.Minute, Delay: 3 * time.Second, DelayKind: SimpleDelay}},
+		{"5b3", TimeControl{Base: 5 * time.Minute, Delay: 3 * time.Second, DelayKind: BronsteinDelay}},
+		{"2:30", TimeControl{Base: 2*time.Minute + 30*time.Second}},
+		{"0.5", TimeControl{Base: 30 * time.Second}},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeControl(tt.s)
+		if err != nil {
+			t.Errorf("ParseTimeControl(%q) failed: %v", tt.s, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTimeControl(%q) = %+v, want %+v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParseTimeControlErrors(t *testing.T) {
+	for _, s := range []string{
+		"", "0", "-5", "abc", "5+", "5+x", "5+-1", "0/5", "x/5", "2:60", "2:-1",
+		"inf", "nan", "1e300", "5+inf", "5+nan", "5d1e300", "5b-inf", "2000",
+	} {
+		if tc, err := ParseTimeControl(s); err == nil {
+			t.Errorf("ParseTimeControl(%q) = %+v, want an error", s, tc)
+		}
+	}
+}
+
+func TestTimeControlString(t *testing.T) {
+	// A tag TimeControl do PGN usa segundos
+	tests := []struct {
+		tc   string
+		want string
+	}{
+		{"5+3", "300+3"},
+		{"40/90", "40/5400"},
+		{"5d3", "300d3"},
+		{"5b0.5", "300b0.5"},
+		{"2:30", "150"},
+	}
+	for _, tt := range tests {
+		tc, err := ParseTimeControl(tt.tc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tc.String(); got != tt.want {
+			t.Errorf("String() of %s = %q, want %q", tt.tc, got, tt.want)
+		}
+	}
+}
+
+// spend simula uma jogada do lado informado que levou o tempo informado
+func spend(c *Clock, color chess.Color, elapsed time.Duration) bool {
+	c.Start(color)
+	c.started = time.Now().Add(-elapsed)
+	return c.Stop(true)
+}
+
+func TestClockIncrement(t *testing.T) {
+	c := NewClock(TimeControl{Base: time.Minute, Increment: 2 * time.Second})
+	if !spend(c, chess.White, 5*time.Second) {
+		t.Fatal("white should still have time")
+	}
+	assertRemaining(t, c, chess.White, 57*time.Second)
+	assertRemaining(t, c, chess.Black, time.Minute)
+}
+
+func TestClockBronsteinDelay(t *testing.T) {
+	c := NewClock(TimeControl{Base: time.Minute, Delay: 3 * time.Second, DelayKind: BronsteinDelay})
+	// O tempo gasto é devolvido até o valor do atraso
+	spend(c, chess.White, 2*time.Second)
+	assertRemaining(t, c, chess.White, time.Minute)
+	spend(c, chess.White, 10*time.Second)
+	assertRemaining(t, c, chess.White, 53*time.Second)
+}
+
+func TestClockSimpleDelay(t *testing.T) {
+	c := NewClock(TimeControl{Base: time.Minute, Delay: 3 * time.Second, DelayKind: SimpleDelay})
+	// O relógio só corre depois do atraso
+	spend(c, chess.Black, 2*time.Second)
+	assertRemaining(t, c, chess.Black, time.Minute)
+	spend(c, chess.Black, 10*time.Second)
+	assertRemaining(t, c, chess.Black, 53*time.Second)
+}
+
+func TestClockSessions(t *testing.T) {
+	c := NewClock(TimeControl{Moves: 2, Base: time.Minute})
+	spend(c, chess.White, 10*time.Second)
+	assertRemaining(t, c, chess.White, 50*time.Second)
+	// A segunda jogada encerra a sessão, que soma o tempo base ao relógio
+	spend(c, chess.White, 10*time.Second)
+	assertRemaining(t, c, chess.White, 100*time.Second)
+	spend(c, chess.White, 10*time.Second)
+	spend(c, chess.White, 10*time.Second)
+	assertRemaining(t, c, chess.White, 140*time.Second)
+}
+
+func TestClockFlag(t *testing.T) {
+	c := NewClock(TimeControl{Base: time.Second, Increment: 5 * time.Second})
+	if spend(c, chess.White, 2*time.Second) {
+		t.Error("white should have run out of time")
+	}
+	assertRemaining(t, c, chess.White, 0)
+}
+
+func TestClockPause(t *testing.T) {
+	c := NewClock(TimeControl{Base: time.Minute})
+	c.Start(chess.White)
+	c.started = time.Now().Add(-5 * time.Second)
+	c.Pause()
+	time.Sleep(20 * time.Millisecond)
+	// O tempo da pausa não é descontado
+	assertRemaining(t, c, chess.White, 55*time.Second)
+	c.Resume()
+	c.Stop(true)
+	if got := c.Remaining(chess.White); got > 55*time.Second || got < 54*time.Second {
+		t.Errorf("Remaining(white) = %s after the pause, want about 55s", got)
+	}
+}
+
+func TestClockWatch(t *testing.T) {
+	c := NewClock(TimeControl{Base: 50 * time.Millisecond})
+	c.Start(chess.White)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Watch(ctx, cancel)
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not cancel the move when the time ran out")
+	}
+	if c.Stop(true) {
+		t.Error("white should have run out of time")
+	}
+}
+
+func TestClockMoveBudget(t *testing.T) {
+	tests := []struct {
+		tc   string
+		want time.Duration
+	}{
+		// 60s divididos por 30 jogadas, mais 3/4 do incremento
+		{"1+4", 5 * time.Second},
+		// O atraso simples é somado uma única vez
+		{"1d4", 6 * time.Second},
+		// O atraso Bronstein é tratado como um incremento
+		{"1b4", 5 * time.Second},
+		// 60s divididos pelas 20 jogadas da sessão
+		{"20/1", 3 * time.Second},
+	}
+	for _, tt := range tests {
+		tc, err := ParseTimeControl(tt.tc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := NewClock(tc).MoveBudget(chess.White); got != tt.want {
+			t.Errorf("MoveBudget with %s = %s, want %s", tt.tc, got, tt.want)
+		}
+	}
+}
+
+func TestLoseOnTime(t *testing.T) {
+	game := chess.NewGame()
+	LoseOnTime(game, chess.White)
+	if game.Outcome() != chess.BlackWon {
+		t.Errorf("outcome = %s, want %s", game.Outcome(), chess.BlackWon)
+	}
+	if got := describeMethod(game); got != "TimeForfeit" {
+		t.Errorf("describeMethod = %q, want TimeForfeit", got)
+	}
+	if got := describeOutcome(game); got != "White loses on time" {
+		t.Errorf("describeOutcome = %q, want %q", got, "White loses on time")
+	}
+
+	// Sem material para dar mate, a queda da bandeira empata a partida
+	option, err := chess.FEN("4k3/8/8/8/8/8/4P3/4K1N1 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	game = chess.NewGame(option)
+	LoseOnTime(game, chess.White)
+	if game.Outcome() != chess.Draw {
+		t.Errorf("black has only the king, so the outcome should be a draw, got %s", game.Outcome())
+	}
+	if got := describeOutcome(game); got != "Time forfeit against insufficient material" {
+		t.Errorf("describeOutcome = %q", got)
+	}
+}
+
+// assertRemaining verifica o tempo restante de um relógio parado
+func assertRemaining(t *testing.T, c *Clock, color chess.Color, want time.Duration) {
+	t.Helper()
+	if got := c.Remaining(color); got < want-50*time.Millisecond || got > want+50*time.Millisecond {
+		t.Errorf("Remaining(%s) = %s, want %s", color.Name(), got, want)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/spf13/viper"
+)
+
+// errGameChanged indica que a partida foi substituída por um comando do
+// jogador humano, como o load, e que o jogo deve continuar a partir da nova
+// partida
+var errGameChanged = errors.New("the game was changed")
+
+// errGameLoaded indica que o jogador humano carregou outra partida, então os
+// jogadores devem ser avisados de que uma nova partida começou
+var errGameLoaded = errors.New("another game was loaded")
+
+// console guarda o estado do jogo interativo no terminal, usado pelos
+// comandos digitados pelo jogador humano e pelo salvamento automático
+type console struct {
+	game         *GameHistory
+	white, black Player
+	// saveFile é o arquivo usado pelo salvamento automático e pelos comandos
+	// save e load quando nenhum arquivo é informado
+	saveFile string
+	// clock é o relógio da partida, quando há controle de tempo
+	clock *Clock
+
+	// snapshot é uma cópia da partida, atualizada a cada jogada, que pode ser
+	// salva por outra goroutine ao receber um sinal de encerramento
+	mu       sync.Mutex
+	snapshot *chess.Game
+}
+
+// newConsole cria o estado do jogo interativo para a partida informada
+func newConsole(game *chess.Game, white, black Player, saveFile string, clock *Clock) *console {
+	c := &console{white: white, black: black, saveFile: saveFile, clock: clock}
+	c.setGame(game)
+	return c
+}
+
+// setGame troca a partida atual, preenchendo as suas tag pairs
+func (c *console) setGame(game *chess.Game) {
+	TagGame(game, c.white.Name(), c.black.Name())
+	if c.clock != nil {
+		game.AddTagPair("TimeControl", c.clock.TimeControl.String())
+	}
+	c.game = NewGameHistory(game)
+	c.update()
+}
+
+// update guarda uma cópia da partida atual para o salvamento automático
+func (c *console) update() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshot = c.game.Copy()
+}
+
+// autosave salva a última cópia da partida no arquivo de salvamento
+func (c *console) autosave() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return SaveGame(c.snapshot, c.saveFile)
+}
+
+// consoleHelp descreve os comandos aceitos no lugar de uma jogada
+const consoleHelp = `Enter a move like 'e4', 'Nf3' or 'g1f3', or one of the commands:
+  r              play a random move
+  undo, takeback take back your last move and the reply to it
+  hint           show the move the AI would play
+  flip           flip the board
+  fen            show the position in FEN
+  pgn            show the game in PGN
+  eval           show the evaluation of the position
+  moves          list the legal moves
+  resign         resign the game
+  draw           claim or offer a draw
+  save [file]    save the game
+  load [file]    load a saved game
+  help           show this help
+  quit           save the game and quit`
+
+// errQuitGame indica que o jogador humano pediu para encerrar o jogo
+var errQuitGame = errors.New("the player quit the game")
+
+// handle trata um comando digitado pelo jogador humano no lugar de uma
+// jogada, retornando false quando a linha não for um comando conhecido.
+// Comandos que alteram a partida retornam errGameChanged, ou errGameLoaded
+// quando a partida é trocada, para que o jogo continue a partir da partida
+// alterada
+func (c *console) handle(game *chess.Game, line string) (bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	path := c.saveFile
+	if len(fields) > 1 {
+		path = fields[1]
+	}
+	switch strings.ToLower(fields[0]) {
+	case "help", "?":
+		fmt.Println(consoleHelp)
+	case "undo", "takeback":
+		return true, c.undo()
+	case "hint":
+		c.hint(game)
+	case "flip":
+		boardFlipped = !boardFlipped
+		PrintBoard(game)
+	case "fen":
+		fmt.Println(game.FEN())
+	case "pgn":
+		TagGame(game, c.white.Name(), c.black.Name())
+		fmt.Println(game.String())
+	case "eval":
+		PrintEvaluation(game.Position())
+	case "moves":
+		moves := make([]string, 0, len(game.ValidMoves()))
+		for _, move := range game.ValidMoves() {
+			moves = append(moves, chess.AlgebraicNotation{}.Encode(game.Position(), move))
+		}
+		sort.Strings(moves)
+		fmt.Println("Legal moves:", strings.Join(moves, " "))
+	case "resign":
+		fmt.Printf("%s resigns\n", game.Position().Turn().Name())
+		game.Resign(game.Position().Turn())
+		return true, errGameChanged
+	case "draw":
+		return true, c.draw(game)
+	case "save":
+		TagGame(game, c.white.Name(), c.black.Name())
+		if err := SaveGame(game, path); err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Println("Game saved to", path)
+		}
+	case "load":
+		loaded, err := LoadStartingGame("", path)
+		if err != nil {
+			fmt.Println(err)
+			return true, nil
+		}
+		fmt.Println("Game loaded from", path)
+		c.setGame(loaded)
+		return true, errGameLoaded
+	case "quit", "exit":
+		return true, errQuitGame
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// opponentOf retorna o jogador das peças da outra cor
+func (c *console) opponentOf(color chess.Color) Player {
+	if color == chess.White {
+		return c.black
+	}
+	return c.white
+}
+
+// undo desfaz a última jogada do humano e a resposta do adversário. Quando
+// os dois jogadores são humanos, somente a última jogada é desfeita
+func (c *console) undo() error {
+	n := 2
+	if _, ok := c.opponentOf(c.game.Position().Turn()).(*HumanPlayer); ok {
+		n = 1
+	}
+	if err := c.game.TakeBack(n); err != nil {
+		fmt.Println(err)
+		return nil
+	}
+	if n == 1 {
+		fmt.Println("Took back the last move")
+	} else {
+		fmt.Printf("Took back the last %d moves\n", n)
+	}
+	c.update()
+	return errGameChanged
+}
+
+// hint exibe a jogada que a IA adversária escolheria na posição atual, com
+// o seu motor e os seus limites de busca, ou a jogada do motor padrão quando
+// o adversário não é a IA. O relógio fica parado enquanto a IA pensa
+func (c *console) hint(game *chess.Game) {
+	engine := aiEngine
+	limits := SearchLimits{Depth: viper.GetInt(DEPTH), MoveTime: viper.GetDuration(MOVETIME)}
+	if ai, ok := c.opponentOf(game.Position().Turn()).(*AIPlayer); ok {
+		engine = ai.Engine
+		limits = ai.SearchLimits(game.Position().Turn())
+	}
+	if c.clock != nil {
+		c.clock.Pause()
+		defer c.clock.Resume()
+	}
+	result, err := engine.IterativeDeepening(context.Background(), game, limits)
+	if err != nil || result == nil || result.Move == nil {
+		fmt.Println("There is no hint for this position")
+		return
+	}
+	fmt.Printf("Hint: %s (score %s)\n", chess.AlgebraicNotation{}.Encode(game.Position(), result.Move), FormatScore(result.Score))
+}
+
+// draw reivindica o empate quando as regras permitem ou o oferece ao
+// adversário
+func (c *console) draw(game *chess.Game) error {
+	// Empates por repetição ou pela regra dos 50 lances podem ser
+	// reivindicados sem depender do adversário
+	for _, method := range game.EligibleDraws() {
+		if method != chess.DrawOffer && game.Draw(method) == nil {
+			fmt.Println("Draw claimed:", describeOutcome(game))
+			return errGameChanged
+		}
+	}
+	color := game.Position().Turn()
+	if !c.acceptsDraw(game, c.opponentOf(color), color.Other()) {
+		fmt.Println("The draw offer was declined")
+		return nil
+	}
+	fmt.Println("The draw offer was accepted")
+	game.Draw(chess.DrawOffer)
+	return errGameChanged
+}
+
+// acceptsDraw pergunta ao adversário se ele aceita o empate. A IA aceita
+// quando não se considera em vantagem, um humano responde pelo teclado e os
+// demais jogadores recusam
+func (c *console) acceptsDraw(game *chess.Game, opponent Player, color chess.Color) bool {
+	switch p := opponent.(type) {
+	case *AIPlayer:
+		score := p.Engine.Evaluator.Evaluate(game.Position())
+		if color == chess.Black {
+			score = -score
+		}
+		return score+p.Engine.Contempt <= 0
+	case *HumanPlayer:
+		fmt.Fprintf(p.Out, "%s, do you accept a draw? (y/n) > ", color.Name())
+		line, err := p.readLine(context.Background())
+		return err == nil && strings.HasPrefix(strings.ToLower(strings.TrimSpace(line)), "y")
+	}
+	return false
+}
+
+// TagGame preenche as tag pairs de uma partida, mantendo o evento, o local e
+// a data de uma partida carregada e atualizando os jogadores e o resultado.
+// As tags seguem a ordem do Seven Tag Roster do PGN, seguidas das demais
+func TagGame(game *chess.Game, white, black string) {
+	tags := [][2]string{
+		{"Event", "puc-chess game"},
+		{"Site", "?"},
+		{"Date", time.Now().Format("2006.01.02")},
+		{"Round", "-"},
+		{"White", white},
+		{"Black", black},
+		{"Result", game.Outcome().String()},
+		{"TimeControl", "-"},
+	}
+	if host, err := os.Hostname(); err == nil {
+		tags[1][1] = host
+	}
+	// Mantém as informações que já estiverem na partida, exceto as que
+	// precisam refletir o jogo atual
+	for i, tag := range tags {
+		if existing := game.GetTagPair(tag[0]); existing != nil && tag[0] != "White" && tag[0] != "Black" && tag[0] != "Result" {
+			tags[i][1] = existing.Value
+		}
+	}
+	others := game.TagPairs()
+	for _, tag := range others {
+		game.RemoveTagPair(tag.Key)
+	}
+	for _, tag := range tags {
+		game.AddTagPair(tag[0], tag[1])
+	}
+	for _, tag := range others {
+		if game.GetTagPair(tag.Key) == nil {
+			game.AddTagPair(tag.Key, tag.Value)
+		}
+	}
+	TagStartingPosition(game)
+}
+
+// SaveGame escreve o PGN da partida no arquivo informado
+func SaveGame(game *chess.Game, path string) error {
+	game.AddTagPair("Result", game.Outcome().String())
+	if err := os.WriteFile(path, []byte(game.String()+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to save the game: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// MatchScore guarda o placar de uma série de partidas do ponto de vista de
+// um dos jogadores
+type MatchScore struct {
+	Wins   int
+	Draws  int
+	Losses int
+}
+
+// Games retorna o número de partidas jogadas
+func (s MatchScore) Games() int {
+	return s.Wins + s.Draws + s.Losses
+}
+
+// Points retorna a pontuação, em que cada vitória vale um ponto e cada
+// empate vale meio ponto
+func (s MatchScore) Points() float64 {
+	return float64(s.Wins) + float64(s.Draws)/2
+}
+
+// Add soma o resultado de uma partida ao placar, sendo 1 uma vitória, 0.5
+// um empate e 0 uma derrota
+func (s *MatchScore) Add(points float64) {
+	switch points {
+	case 1:
+		s.Wins++
+	case 0:
+		s.Losses++
+	default:
+		s.Draws++
+	}
+}
+
+// Reverse retorna o mesmo placar do ponto de vista do adversário
+func (s MatchScore) Reverse() MatchScore {
+	return MatchScore{Wins: s.Losses, Draws: s.Draws, Losses: s.Wins}
+}
+
+// String exibe o placar no formato "vitórias-empates-derrotas"
+func (s MatchScore) String() string {
+	return fmt.Sprintf("+%d =%d -%d", s.Wins, s.Draws, s.Losses)
+}
+
+// EloDifference estima a diferença de Elo entre o jogador e o adversário a
+// partir do placar, junto com a margem de erro para 95% de confiança
+func (s MatchScore) EloDifference() (float64, float64) {
+	n := float64(s.Games())
+	if n == 0 {
+		return 0, 0
+	}
+	score := s.Points() / n
+	// A variância é calculada a partir do resultado de cada partida
+	variance := (float64(s.Wins)*math.Pow(1-score, 2) +
+		float64(s.Draws)*math.Pow(0.5-score, 2) +
+		float64(s.Losses)*math.Pow(score, 2)) / n
+	margin := 1.96 * math.Sqrt(variance/n)
+	return eloFromScore(score), (eloFromScore(score+margin) - eloFromScore(score-margin)) / 2
+}
+
+// eloFromScore converte a fração dos pontos obtidos em diferença de Elo
+// segundo o modelo logístico. Placares de 0% e 100% não têm diferença
+// finita, então são limitados a ±800
+func eloFromScore(score float64) float64 {
+	const limit = 800
+	if score <= 0 {
+		return -limit
+	} else if score >= 1 {
+		return limit
+	}
+	return math.Max(-limit, math.Min(limit, -400*math.Log10(1/score-1)))
+}
+
+// FormatElo exibe uma diferença de Elo com a sua margem de erro
+func FormatElo(elo, margin float64) string {
+	// Evita exibir "-0.0" quando os jogadores empatam
+	if elo == 0 {
+		elo = 0
+	}
+	return fmt.Sprintf("%+.1f +/- %.1f", elo, margin)
+}
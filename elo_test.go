@@ -0,0 +1,43 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEloDifference(t *testing.T) {
+	tests := []struct {
+		score      MatchScore
+		elo        float64
+		margin     float64
+		marginTest bool
+	}{
+		{MatchScore{}, 0, 0, true},
+		{MatchScore{Wins: 10, Draws: 10, Losses: 10}, 0, 0, false},
+		{MatchScore{Wins: 3, Losses: 1}, 190.85, 0, false},
+		{MatchScore{Wins: 1, Losses: 3}, -190.85, 0, false},
+		// Mesmo placar de referência do cutechess-cli
+		{MatchScore{Wins: 60, Draws: 20, Losses: 20}, 147.19, 66.01, true},
+		{MatchScore{Wins: 5}, 800, 0, true},
+		{MatchScore{Losses: 5}, -800, 0, true},
+	}
+	for _, tt := range tests {
+		elo, margin := tt.score.EloDifference()
+		if math.Abs(elo-tt.elo) > 0.01 {
+			t.Errorf("EloDifference(%s) elo = %.2f, want %.2f", tt.score, elo, tt.elo)
+		}
+		if tt.marginTest && math.Abs(margin-tt.margin) > 0.01 {
+			t.Errorf("EloDifference(%s) margin = %.2f, want %.2f", tt.score, margin, tt.margin)
+		}
+	}
+}
+
+func TestMatchScoreReverse(t *testing.T) {
+	score := MatchScore{Wins: 60, Draws: 20, Losses: 20}
+	elo, margin := score.EloDifference()
+	reversedElo, reversedMargin := score.Reverse().EloDifference()
+	if math.Abs(elo+reversedElo) > 1e-9 || math.Abs(margin-reversedMargin) > 1e-9 {
+		t.Errorf("the reversed score should have the opposite Elo difference, got %.2f +/- %.2f and %.2f +/- %.2f",
+			elo, margin, reversedElo, reversedMargin)
+	}
+}
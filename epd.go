@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/spf13/viper"
+)
+
+// EPDCommand é o nome do comando que executa uma bateria de testes táticos
+// no formato EPD
+const EPDCommand = "epd"
+
+// EPDRecord é uma linha de um arquivo EPD: uma posição seguida de operações
+// como "bm Qxf7+;" (melhor jogada), "am e4;" (jogada a evitar) e "id "WAC.001";"
+type EPDRecord struct {
+	FEN string
+	Ops map[string]string
+}
+
+// ParseEPD interpreta uma linha EPD. Como o EPD não tem os contadores de
+// jogadas, eles são preenchidos a partir da operação hmvc, quando houver
+func ParseEPD(line string) (*EPDRecord, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("invalid EPD %q, it should have at least 4 fields", line)
+	}
+	record := &EPDRecord{Ops: map[string]string{}}
+	rest := strings.Join(fields[4:], " ")
+	// Linhas FEN completas também são aceitas
+	if len(fields) >= 6 && isNumber(fields[4]) && isNumber(fields[5]) {
+		record.FEN = strings.Join(fields[:6], " ")
+		rest = strings.Join(fields[6:], " ")
+	}
+	for _, op := range splitEPDOps(rest) {
+		name, operand, _ := strings.Cut(op, " ")
+		record.Ops[name] = strings.Trim(strings.TrimSpace(operand), `"`)
+	}
+	if record.FEN == "" {
+		halfMoves := record.Ops["hmvc"]
+		if halfMoves == "" {
+			halfMoves = "0"
+		}
+		fullMoves := record.Ops["fmvn"]
+		if fullMoves == "" {
+			fullMoves = "1"
+		}
+		record.FEN = strings.Join(append(fields[:4:4], halfMoves, fullMoves), " ")
+	}
+	if _, err := chess.FEN(record.FEN); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// splitEPDOps separa as operações de uma linha EPD, que terminam em ";",
+// sem separar os textos entre aspas
+func splitEPDOps(s string) []string {
+	var ops []string
+	var current strings.Builder
+	quoted := false
+	for _, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			current.WriteRune(c)
+		case c == ';' && !quoted:
+			if op := strings.TrimSpace(current.String()); op != "" {
+				ops = append(ops, op)
+			}
+			current.Reset()
+		default:
+			current.WriteRune(c)
+		}
+	}
+	if op := strings.TrimSpace(current.String()); op != "" {
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// isNumber verifica se o texto é um número inteiro não negativo
+func isNumber(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Game cria uma partida a partir da posição do registro
+func (r *EPDRecord) Game() *chess.Game {
+	fen, _ := chess.FEN(r.FEN)
+	return chess.NewGame(fen)
+}
+
+// ID retorna o identificador da posição, ou o número da linha se ele não
+// for informado
+func (r *EPDRecord) ID(line int) string {
+	if id := r.Ops["id"]; id != "" {
+		return id
+	}
+	return fmt.Sprintf("line %d", line)
+}
+
+// Solves verifica se a jogada resolve a posição, isto é, se ela é uma das
+// melhores jogadas (bm) e não é uma das jogadas a evitar (am)
+func (r *EPDRecord) Solves(pos *chess.Position, move *chess.Move) bool {
+	san := normalizeSAN(chess.AlgebraicNotation{}.Encode(pos, move))
+	if bm, ok := r.Ops["bm"]; ok && !containsSAN(bm, san) {
+		return false
+	}
+	if am, ok := r.Ops["am"]; ok && containsSAN(am, san) {
+		return false
+	}
+	return true
+}
+
+// containsSAN verifica se a jogada está na lista de jogadas de uma operação
+func containsSAN(moves, san string) bool {
+	for _, m := range strings.Fields(moves) {
+		if normalizeSAN(m) == san {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeSAN remove as anotações de xeque e de qualidade de uma jogada,
+// que nem sempre estão presentes nos arquivos EPD
+func normalizeSAN(san string) string {
+	return strings.TrimRight(san, "+#!?")
+}
+
+// EPDResult é o resultado da busca em uma das posições da bateria
+type EPDResult struct {
+	ID     string
+	Move   string
+	Solved bool
+	// SolveTime é o tempo da iteração a partir da qual a busca passou a
+	// escolher sempre uma jogada correta
+	SolveTime time.Duration
+	Expected  string
+}
+
+// RunEPD executa a busca da IA em cada posição do arquivo EPD informado,
+// chamando onResult ao fim de cada posição
+func RunEPD(ctx context.Context, in io.Reader, engine *Engine, limits SearchLimits, onResult func(EPDResult)) ([]EPDResult, error) {
+	var results []EPDResult
+	scanner := bufio.NewScanner(in)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		record, err := ParseEPD(text)
+		if err != nil {
+			return results, fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.Ops["bm"] == "" && record.Ops["am"] == "" {
+			return results, fmt.Errorf("line %d: the position has neither a bm nor an am operation", line)
+		}
+
+		game := record.Game()
+		pos := game.Position()
+		solvedAt := time.Duration(-1)
+		engine.NewGame()
+		engine.OnIteration = func(r *SearchResult) {
+			if !record.Solves(pos, r.Move) {
+				solvedAt = -1
+			} else if solvedAt < 0 {
+				solvedAt = r.Time
+			}
+		}
+		best, err := engine.IterativeDeepening(ctx, game, limits)
+		engine.OnIteration = nil
+		if err != nil {
+			return results, fmt.Errorf("line %d: %w", line, err)
+		}
+		if best == nil || best.Move == nil {
+			return results, fmt.Errorf("line %d: the position has no legal moves", line)
+		}
+
+		result := EPDResult{
+			ID:     record.ID(line),
+			Move:   chess.AlgebraicNotation{}.Encode(pos, best.Move),
+			Solved: record.Solves(pos, best.Move),
+		}
+		if result.Solved {
+			result.SolveTime = solvedAt
+		}
+		if bm, ok := record.Ops["bm"]; ok {
+			result.Expected = "bm " + bm
+		} else {
+			result.Expected = "am " + record.Ops["am"]
+		}
+		results = append(results, result)
+		if onResult != nil {
+			onResult(result)
+		}
+	}
+	return results, scanner.Err()
+}
+
+// RunEPDCommand executa o comando epd com os argumentos de linha de comando,
+// exibindo o resultado de cada posição e o resumo da bateria
+func RunEPDCommand(out io.Writer) error {
+	path := viper.GetString(SUITE)
+	if path == "" {
+		return fmt.Errorf("missing the EPD file, use --%s", SUITE)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	limits := SearchLimits{Depth: viper.GetInt(DEPTH), MoveTime: viper.GetDuration(MOVETIME)}
+	t1 := time.Now()
+	results, err := RunEPD(context.Background(), f, aiEngine, limits, func(r EPDResult) {
+		if r.Solved {
+			fmt.Fprintf(out, "%s: solved with %s in %s\n", r.ID, r.Move, r.SolveTime)
+		} else {
+			fmt.Fprintf(out, "%s: FAILED with %s, expected %s\n", r.ID, r.Move, r.Expected)
+		}
+	})
+
+	solved := 0
+	var solveTime time.Duration
+	for _, r := range results {
+		if r.Solved {
+			solved++
+			solveTime += r.SolveTime
+		}
+	}
+	if len(results) > 0 {
+		fmt.Fprintf(out, "Solved %d of %d positions (%.1f%%) in %s\n",
+			solved, len(results), 100*float64(solved)/float64(len(results)), time.Since(t1))
+	}
+	if solved > 0 {
+		fmt.Fprintf(out, "Average solve time: %s\n", solveTime/time.Duration(solved))
+	}
+	return err
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseEPD(t *testing.T) {
+	tests := []struct {
+		line string
+		fen  string
+		ops  map[string]string
+	}{
+		{
+			// O ; dentro das aspas não encerra a operação
+			`6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - bm Ra8#; id "mate; back rank";`,
+			"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1",
+			map[string]string{"bm": "Ra8#", "id": "mate; back rank"},
+		},
+		{
+			"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 3 40 bm Ra8; id \"full FEN\";",
+			"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 3 40",
+			map[string]string{"bm": "Ra8", "id": "full FEN"},
+		},
+		{
+			"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - hmvc 12; fmvn 30; bm Ra8+;",
+			"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 12 30",
+			map[string]string{"hmvc": "12", "fmvn": "30", "bm": "Ra8+"},
+		},
+		{
+			// Uma lista de jogadas e a última operação sem ;
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - bm e4 d4 c4; am a4",
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			map[string]string{"bm": "e4 d4 c4", "am": "a4"},
+		},
+	}
+	for _, tt := range tests {
+		record, err := ParseEPD(tt.line)
+		if err != nil {
+			t.Errorf("ParseEPD(%q) failed: %v", tt.line, err)
+			continue
+		}
+		if record.FEN != tt.fen {
+			t.Errorf("ParseEPD(%q).FEN = %q, want %q", tt.line, record.FEN, tt.fen)
+		}
+		if len(record.Ops) != len(tt.ops) {
+			t.Errorf("ParseEPD(%q).Ops = %q, want %q", tt.line, record.Ops, tt.ops)
+		}
+		for name, want := range tt.ops {
+			if got := record.Ops[name]; got != want {
+				t.Errorf("ParseEPD(%q).Ops[%s] = %q, want %q", tt.line, name, got, want)
+			}
+		}
+	}
+}
+
+func TestParseEPDErrors(t *testing.T) {
+	for _, line := range []string{
+		"6k1/5ppp/8 w -",
+		"6k1/5ppp/8/8/8/8/5PPP/R5K1 x - - bm Ra8;",
+		"not/a/valid/board w - - bm Ra8;",
+	} {
+		if _, err := ParseEPD(line); err == nil {
+			t.Errorf("ParseEPD(%q) should have failed", line)
+		}
+	}
+}
+
+func TestEPDSolves(t *testing.T) {
+	const fen = "6k1/5ppp/8/8/8/8/5PPP/R5K1 w - -"
+	tests := []struct {
+		ops    string
+		move   string
+		solves bool
+	}{
+		// As anotações de xeque e mate são opcionais
+		{"bm Ra8;", "a1a8", true},
+		{"bm Ra8+;", "a1a8", true},
+		{"bm Ra8#;", "a1a8", true},
+		{"bm Ra7 Ra8;", "a1a8", true},
+		{"bm Ra7;", "a1a8", false},
+		// Sem bm, qualquer jogada que não esteja no am resolve
+		{"am Ra8;", "a1a8", false},
+		{"am Ra8;", "a1a7", true},
+		{"bm Ra8; am Ra8;", "a1a8", false},
+	}
+	for _, tt := range tests {
+		record, err := ParseEPD(fen + " " + tt.ops)
+		if err != nil {
+			t.Fatal(err)
+		}
+		game := record.Game()
+		if err := MoveUCI(game, tt.move); err != nil {
+			t.Fatal(err)
+		}
+		pos, move := game.Positions()[0], game.Moves()[0]
+		if got := record.Solves(pos, move); got != tt.solves {
+			t.Errorf("%s with %s: Solves = %t, want %t", tt.ops, tt.move, got, tt.solves)
+		}
+	}
+}
+
+func TestRunEPD(t *testing.T) {
+	suite := strings.Join([]string{
+		"# Mates em 1",
+		`6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - bm Ra8#; id "back rank";`,
+		`r5k1/5ppp/8/8/8/8/5PPP/6K1 b - - bm Ra1+; id "back rank; black";`,
+		"",
+		"7k/8/5KQ1/8/8/8/8/8 w - - bm Qg7#;",
+		// A dama não pode capturar o peão protegido
+		`4k3/8/4p3/3p4/8/8/8/3QK3 w - - am Qxd5; id "defended pawn";`,
+	}, "\n")
+	var reported []EPDResult
+	results, err := RunEPD(context.Background(), strings.NewReader(suite), NewEngine(1), SearchLimits{Depth: 3}, func(r EPDResult) {
+		reported = append(reported, r)
+	})
+	if err != nil {
+		t.Fatalf("RunEPD failed: %v", err)
+	}
+	want := []struct {
+		id, move, expected string
+	}{
+		{"back rank", "Ra8#", "bm Ra8#"},
+		{"back rank; black", "Ra1#", "bm Ra1+"},
+		{"line 5", "Qg7#", "bm Qg7#"},
+		{"defended pawn", "", "am Qxd5"},
+	}
+	if len(results) != len(want) || len(reported) != len(want) {
+		t.Fatalf("got %d results and %d reported, want %d", len(results), len(reported), len(want))
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.ID != w.id || !r.Solved || r.Expected != w.expected || (w.move != "" && r.Move != w.move) {
+			t.Errorf("result %d = %+v, want %s solved with %q, expected %q", i+1, r, w.id, w.move, w.expected)
+		}
+		if r.SolveTime < 0 {
+			t.Errorf("%s: the solve time %s should not be negative", r.ID, r.SolveTime)
+		}
+	}
+
+	// Uma posição sem bm nem am é um erro
+	if _, err := RunEPD(context.Background(), strings.NewReader("6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - id \"x\";"), NewEngine(1), SearchLimits{Depth: 1}, nil); err == nil {
+		t.Error("RunEPD should fail on a position without bm or am")
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/notnil/chess"
+)
+
+// Nomes dos avaliadores que podem ser escolhidos pela linha de comando
+const (
+	MaterialEvaluatorName   = "material"
+	PositionalEvaluatorName = "positional"
+)
+
+// Evaluator avalia uma posição do ponto de vista das peças brancas, ou seja,
+// valores positivos indicam que as brancas estão ganhando
+type Evaluator interface {
+	Evaluate(pos *chess.Position) int
+}
+
+// EvaluationTerm é uma das parcelas que compõem a avaliação de uma posição
+type EvaluationTerm struct {
+	Name  string
+	Score int
+}
+
+// Explainer é implementado pelos avaliadores capazes de detalhar cada termo
+// que compõe a sua avaliação
+type Explainer interface {
+	Explain(pos *chess.Position) []EvaluationTerm
+}
+
+// CentipawnScaler é implementado pelos avaliadores cujas avaliações não estão
+// em centipeões, informando quantos centipeões vale cada ponto delas
+type CentipawnScaler interface {
+	CentipawnsPerPoint() int
+}
+
+// MaterialEvaluator avalia uma posição contando apenas o material de cada
+// lado, da mesma forma que o EvaluateStrongerSide
+type MaterialEvaluator struct{}
+
+// Evaluate implementa a interface Evaluator
+func (MaterialEvaluator) Evaluate(pos *chess.Position) int {
+	return EvaluatePosition(pos)
+}
+
+// CentipawnsPerPoint implementa a interface CentipawnScaler. O peão vale 10
+// no EvaluatePosition
+func (MaterialEvaluator) CentipawnsPerPoint() int {
+	return 10
+}
+
+// Centipawns converte uma avaliação do avaliador informado para centipeões,
+// a unidade esperada pelas interfaces gráficas. Os mates não são convertidos
+func Centipawns(evaluator Evaluator, score int) int {
+	if score >= maxMateScore || score <= -maxMateScore {
+		return score
+	}
+	if scaler, ok := evaluator.(CentipawnScaler); ok {
+		return score * scaler.CentipawnsPerPoint()
+	}
+	return score
+}
+
+// NewEvaluator cria o avaliador com o nome informado
+func NewEvaluator(name string) (Evaluator, error) {
+	switch name {
+	case MaterialEvaluatorName:
+		return MaterialEvaluator{}, nil
+	case PositionalEvaluatorName:
+		return NewPositionalEvaluator(), nil
+	}
+	return nil, fmt.Errorf("unknown evaluator %q, it should be %q or %q", name, MaterialEvaluatorName, PositionalEvaluatorName)
+}
+
+// mateScore é a avaliação de uma posição em que quem joga já levou xeque-mate.
+// Os mates são pontuados como mateScore menos a distância até eles, de forma
+// que a busca sempre prefira os mais curtos
+const mateScore = 100000
+
+// maxMateScore é a menor avaliação, em módulo, que ainda representa um mate
+const maxMateScore = mateScore - 2*maxSearchDepth
+
+// evaluateTerminal verifica se a posição encerra a partida por xeque-mate,
+// afogamento, material insuficiente ou pela regra dos cinquenta lances. Caso
+// encerre, retorna a avaliação do ponto de vista de quem joga nela, em que
+// ply é a distância até a raiz da busca e drawScore é o valor de um empate
+func evaluateTerminal(pos *chess.Position, moves []*chess.Move, ply, drawScore int) (int, bool) {
+	if len(moves) == 0 {
+		if pos.Status() == chess.Checkmate {
+			return -mateScore + ply, true
+		}
+		return drawScore, true
+	}
+	if halfMoveClock(pos) >= 100 || insufficientMaterial(pos.Board()) {
+		return drawScore, true
+	}
+	return 0, false
+}
+
+// halfMoveClock retorna o número de meias-jogadas desde a última captura ou
+// movimento de peão, usado pela regra dos cinquenta lances
+func halfMoveClock(pos *chess.Position) int {
+	data, _ := pos.MarshalBinary()
+	return int(data[96])
+}
+
+// insufficientMaterial verifica se nenhum dos lados possui material
+// suficiente para dar xeque-mate
+func insufficientMaterial(board *chess.Board) bool {
+	knights := 0
+	bishops := [2]int{}
+	for sq, piece := range board.SquareMap() {
+		switch piece.Type() {
+		case chess.Queen, chess.Rook, chess.Pawn:
+			return false
+		case chess.Knight:
+			knights++
+		case chess.Bishop:
+			// Bispos que andam por casas da mesma cor nunca conseguem dar
+			// xeque-mate sozinhos
+			bishops[(int(sq.File())+int(sq.Rank()))%2]++
+		}
+	}
+	if knights == 0 {
+		return bishops[0] == 0 || bishops[1] == 0
+	}
+	return knights == 1 && bishops[0]+bishops[1] == 0
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestEvaluateTerminal(t *testing.T) {
+	const draw = -7
+	tests := []struct {
+		name     string
+		fen      string
+		ply      int
+		want     int
+		terminal bool
+	}{
+		{"checkmate", "R5k1/5ppp/8/8/8/8/5PPP/6K1 b - - 1 1", 1, -mateScore + 1, true},
+		{"checkmate deeper in the search", "R5k1/5ppp/8/8/8/8/5PPP/6K1 b - - 1 1", 5, -mateScore + 5, true},
+		{"stalemate", "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", 3, draw, true},
+		{"bishops on the same colour", "4k3/8/8/3b4/8/8/2B5/4K3 w - - 0 1", 2, draw, true},
+		{"bishops on different colours", "4k3/8/8/3b4/8/8/3B4/4K3 w - - 0 1", 2, 0, false},
+		{"lone knight", "4k3/8/8/8/8/8/8/3NK3 w - - 0 1", 2, draw, true},
+		{"two knights", "4k3/8/8/8/8/8/8/2NNK3 w - - 0 1", 2, 0, false},
+		{"fifty moves", "4k3/8/8/8/8/8/8/R3K3 w - - 100 80", 2, draw, true},
+		{"one ply before fifty moves", "4k3/8/8/8/8/8/8/R3K3 w - - 99 80", 2, 0, false},
+		{"pawns left", "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", 2, 0, false},
+	}
+	for _, tt := range tests {
+		pos := gameFromFEN(t, tt.fen).Position()
+		score, terminal := evaluateTerminal(pos, pos.ValidMoves(), tt.ply, draw)
+		if terminal != tt.terminal || score != tt.want {
+			t.Errorf("%s: evaluateTerminal = %d, %t, want %d, %t", tt.name, score, terminal, tt.want, tt.terminal)
+		}
+	}
+}
+
+func TestHalfMoveClock(t *testing.T) {
+	for _, want := range []int{0, 1, 37, 100} {
+		pos := gameFromFEN(t, "4k3/8/8/8/8/8/8/R3K3 w - - "+strconv.Itoa(want)+" 80").Position()
+		if got := halfMoveClock(pos); got != want {
+			t.Errorf("halfMoveClock = %d, want %d", got, want)
+		}
+	}
+}
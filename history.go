@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// GameHistory é uma partida que permite desfazer jogadas. O chess.Game não
+// tem como voltar jogadas, então a partida é reconstruída a partir da
+// posição inicial com as jogadas restantes, mantendo as tag pairs e os
+// comentários de cada jogada
+type GameHistory struct {
+	*chess.Game
+}
+
+// NewGameHistory cria o histórico da partida informada
+func NewGameHistory(game *chess.Game) *GameHistory {
+	return &GameHistory{Game: game}
+}
+
+// TakeBack desfaz as últimas n jogadas da partida. Uma partida terminada
+// volta a estar em andamento
+func (h *GameHistory) TakeBack(n int) error {
+	moves := h.Moves()
+	if n <= 0 || n > len(moves) {
+		return fmt.Errorf("there are no moves to take back")
+	}
+	game, err := rebuildGame(h.Game, len(moves)-n, chess.NoOutcome, chess.NoMethod)
+	if err != nil {
+		return err
+	}
+	h.Game = game
+	return nil
+}
+
+// Copy retorna uma cópia da partida. Ao contrário do Clone do chess.Game,
+// a cópia mantém os comentários das jogadas
+func (h *GameHistory) Copy() *chess.Game {
+	game, err := rebuildGame(h.Game, len(h.Moves()), h.Outcome(), h.Method())
+	if err != nil {
+		return h.Clone()
+	}
+	return game
+}
+
+// rebuildGame reconstrói a partida com as suas primeiras jogadas, terminada
+// com o resultado e a forma de término informados. Os comentários só podem
+// ser adicionados a um chess.Game ao ler um PGN, então a partida é
+// reconstruída escrevendo e lendo de volta o PGN dessas jogadas
+func rebuildGame(game *chess.Game, plies int, outcome chess.Outcome, method chess.Method) (*chess.Game, error) {
+	var pgn strings.Builder
+	// A posição inicial de uma partida que não começa da posição padrão só
+	// é lida de volta pela tag FEN. As tag pairs são copiadas depois da
+	// leitura, já que o chess.Game não lê valores com aspas escapadas
+	positions := game.Positions()
+	if start := positions[0].String(); start != chess.StartingPosition().String() {
+		fmt.Fprintf(&pgn, "[FEN \"%s\"]\n", start)
+	}
+	pgn.WriteString("\n")
+
+	// Os números das jogadas são ignorados na leitura, então não são
+	// escritos. Um comentário do PGN não tem como conter o "}" que o
+	// encerra, e os comentários lidos de um PGN nunca o contêm
+	moves, comments := game.Moves(), game.Comments()
+	for i, move := range moves[:plies] {
+		pgn.WriteString(chess.AlgebraicNotation{}.Encode(positions[i], move) + " ")
+		if i < len(comments) {
+			for _, comment := range comments[i] {
+				if strings.Contains(comment, "}") {
+					return nil, fmt.Errorf("failed to rebuild the game: the comment %q cannot be written to PGN", comment)
+				}
+				fmt.Fprintf(&pgn, "{ %s } ", comment)
+			}
+		}
+	}
+	// O PGN não registra a forma de término, então o abandono e os empates
+	// reivindicados são refeitos depois da leitura. Os empates automáticos
+	// só são reconhecidos pelo chess.Game ao fazer as jogadas, e não ao ler
+	// um PGN, então nesse caso somente o resultado é mantido
+	result := outcome
+	if outcome != chess.NoOutcome && restorableMethod(method) {
+		result = chess.NoOutcome
+	}
+	pgn.WriteString(string(result))
+
+	option, err := chess.PGN(strings.NewReader(pgn.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild the game: %w", err)
+	}
+	rebuilt := chess.NewGame(option)
+	for _, tag := range rebuilt.TagPairs() {
+		rebuilt.RemoveTagPair(tag.Key)
+	}
+	for _, tag := range game.TagPairs() {
+		rebuilt.AddTagPair(tag.Key, tag.Value)
+	}
+	if result != outcome {
+		if method == chess.Resignation {
+			loser := chess.White
+			if outcome == chess.WhiteWon {
+				loser = chess.Black
+			}
+			rebuilt.Resign(loser)
+		} else if err := rebuilt.Draw(method); err != nil {
+			return nil, fmt.Errorf("failed to rebuild the game: %w", err)
+		}
+	}
+	return rebuilt, nil
+}
+
+// restorableMethod verifica se a forma de término pode ser refeita em uma
+// partida lida de um PGN
+func restorableMethod(method chess.Method) bool {
+	switch method {
+	case chess.Resignation, chess.DrawOffer, chess.ThreefoldRepetition, chess.FiftyMoveRule:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// commentedGame retorna uma partida lida de um PGN, com tag pairs e
+// comentários
+func commentedGame(t *testing.T) *chess.Game {
+	t.Helper()
+	pgn := `[Event "Club \"Open\" championship"]
+[Site "Rio de Janeiro"]
+
+1. e4 { best by test } e5 2. Nf3 { develops [the knight] } Nc6 { defends e5 } 3. Bb5 *`
+	option, err := chess.PGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := chess.NewGame(option)
+	// O chess.Game não lê aspas escapadas, então o valor é trocado aqui
+	game.AddTagPair("Event", `Club "Open" championship`)
+	return game
+}
+
+func TestGameHistoryTakeBack(t *testing.T) {
+	history := NewGameHistory(commentedGame(t))
+	if err := history.TakeBack(2); err != nil {
+		t.Fatalf("TakeBack failed: %v", err)
+	}
+
+	if got := FormatMoves(history.Moves()); got != "e2e4 e7e5 g1f3" {
+		t.Errorf("moves after the takeback = %s, want e2e4 e7e5 g1f3", got)
+	}
+	for key, want := range map[string]string{"Event": `Club "Open" championship`, "Site": "Rio de Janeiro"} {
+		if tag := history.GetTagPair(key); tag == nil || tag.Value != want {
+			t.Errorf("tag %s = %v, want %q", key, tag, want)
+		}
+	}
+	comments := history.Comments()
+	want := [][]string{{"best by test"}, nil, {"develops [the knight]"}}
+	for i := range want {
+		if i >= len(comments) || strings.Join(comments[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("comments after the takeback = %q, want %q", comments, want)
+			break
+		}
+	}
+
+	if err := history.TakeBack(4); err == nil {
+		t.Error("taking back more moves than were played should fail")
+	}
+}
+
+func TestGameHistoryTakeBackFromPosition(t *testing.T) {
+	option, err := chess.FEN("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := NewGameHistory(chess.NewGame(option))
+	history.MoveStr("e4")
+	history.MoveStr("Kd7")
+	if err := history.TakeBack(1); err != nil {
+		t.Fatalf("TakeBack failed: %v", err)
+	}
+	if got := history.FEN(); got != "4k3/8/8/8/4P3/8/8/4K3 b - e3 0 1" {
+		t.Errorf("position after the takeback = %s", got)
+	}
+	if history.GetTagPair("FEN") != nil {
+		t.Error("the takeback should not add a FEN tag")
+	}
+}
+
+func TestGameHistoryCopyKeepsMethod(t *testing.T) {
+	history := NewGameHistory(commentedGame(t))
+	history.Resign(chess.Black)
+	game := history.Copy()
+	if game.Outcome() != chess.WhiteWon || game.Method() != chess.Resignation {
+		t.Errorf("copy ended with %s by %s, want %s by %s", game.Outcome(), game.Method(), chess.WhiteWon, chess.Resignation)
+	}
+	if len(game.Comments()) == 0 || len(game.Comments()[0]) == 0 {
+		t.Error("the copy lost the comments")
+	}
+
+	history = NewGameHistory(commentedGame(t))
+	history.Draw(chess.DrawOffer)
+	if game := history.Copy(); game.Outcome() != chess.Draw || game.Method() != chess.DrawOffer {
+		t.Errorf("copy ended with %s by %s, want %s by %s", game.Outcome(), game.Method(), chess.Draw, chess.DrawOffer)
+	}
+}
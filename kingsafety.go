@@ -0,0 +1,98 @@
+package main
+
+import (
+	"math/bits"
+
+	"github.com/notnil/chess"
+)
+
+// Termos da segurança do rei, em centipeões. Eles valem apenas no
+// meio-jogo, já que no final o rei precisa participar do jogo
+const (
+	// Bônus para cada peão do escudo na fileira logo à frente do rei e na
+	// fileira seguinte
+	pawnShieldBonus     = 12
+	farPawnShieldBonus  = 6
+	semiOpenFilePenalty = 15
+	openFilePenalty     = 25
+	maxKingAttackScore  = 400
+)
+
+// kingAttackWeights indica o quanto cada peça adversária que ataca a região
+// do rei contribui para o perigo, indexados pelo chess.PieceType
+var kingAttackWeights = [7]int{chess.Queen: 5, chess.Rook: 3, chess.Bishop: 2, chess.Knight: 2}
+
+// evaluateKingSafety avalia a segurança dos reis do ponto de vista das
+// peças brancas
+func evaluateKingSafety(bs *boardState, whitePawns, blackPawns uint64) (int, int) {
+	return kingSafetyOf(bs, chess.White, whitePawns, blackPawns) - kingSafetyOf(bs, chess.Black, blackPawns, whitePawns), 0
+}
+
+// kingSafetyOf avalia a segurança do rei de uma cor, dados os seus peões e
+// os peões do adversário, considerando o escudo de peões à sua frente, as
+// colunas abertas ao seu redor e as peças adversárias que atacam a sua região
+func kingSafetyOf(bs *boardState, c chess.Color, ownPawns, enemyPawns uint64) int {
+	king := bs.kings[colorIndex(c)]
+	if king == chess.NoSquare {
+		return 0
+	}
+	file, rank := int(king)%8, int(king)/8
+	forward := 1
+	if c == chess.Black {
+		forward = -1
+	}
+	files := fileMask(file) | adjacentFilesMask(file)
+	score := 0
+
+	// Escudo de peões, considerado apenas enquanto o rei está na primeira ou
+	// na segunda fileira do seu lado
+	relativeRank := rank
+	if c == chess.Black {
+		relativeRank = 7 - rank
+	}
+	if relativeRank <= 1 {
+		if r := rank + forward; r >= 0 && r < 8 {
+			score += pawnShieldBonus * bits.OnesCount64(ownPawns&files&rankMask(r))
+		}
+		if r := rank + 2*forward; r >= 0 && r < 8 {
+			score += farPawnShieldBonus * bits.OnesCount64(ownPawns&files&rankMask(r))
+		}
+	}
+
+	// Colunas sem peões próprios perto do rei abrem caminho para as torres
+	// e a dama do adversário
+	for f := file - 1; f <= file+1; f++ {
+		if f < 0 || f > 7 || ownPawns&fileMask(f) != 0 {
+			continue
+		}
+		if enemyPawns&fileMask(f) == 0 {
+			score -= openFilePenalty
+		} else {
+			score -= semiOpenFilePenalty
+		}
+	}
+
+	// Peças adversárias que atacam a região do rei, formada pela casa do rei
+	// e as casas vizinhas. Uma peça sozinha raramente é perigosa, então a
+	// penalidade só é aplicada a partir de dois atacantes e cresce rápido
+	zone := kingAttackTable[king] | 1<<uint(king)
+	attackers, units := 0, 0
+	for sq := chess.Square(0); sq < 64; sq++ {
+		piece := bs.pieces[sq]
+		if piece == chess.NoPiece || piece.Color() == c || kingAttackWeights[piece.Type()] == 0 {
+			continue
+		}
+		if hits := bits.OnesCount64(bs.attacksFrom(sq) & zone); hits > 0 {
+			attackers++
+			units += kingAttackWeights[piece.Type()] * hits
+		}
+	}
+	if attackers >= 2 {
+		penalty := units * units / 4
+		if penalty > maxKingAttackScore {
+			penalty = maxKingAttackScore
+		}
+		score -= penalty
+	}
+	return score
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestKingSafety(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want int
+	}{
+		{"full shield", "k7/8/8/8/8/8/5PPP/6K1 w - - 0 1", 3 * pawnShieldBonus},
+		{"advanced shield pawn", "k7/8/8/8/8/6P1/5P1P/6K1 w - - 0 1", 2*pawnShieldBonus + farPawnShieldBonus},
+		{"semi-open file", "k7/6p1/8/8/8/8/5P1P/6K1 w - - 0 1", 2*pawnShieldBonus - semiOpenFilePenalty},
+		{"open file", "k7/8/8/8/8/8/5P1P/6K1 w - - 0 1", 2*pawnShieldBonus - openFilePenalty},
+		// Longe da primeira fileira o escudo de peões não conta
+		{"king away from home", "k7/8/8/8/3PPP2/4K3/8/8 w - - 0 1", 0},
+		// Um único atacante não é penalizado
+		{"one attacker", "k7/8/8/8/7q/8/5PPP/6K1 w - - 0 1", 3 * pawnShieldBonus},
+		// A dama e o cavalo atacam f2 e h2, somando 2*5 + 2*2 unidades
+		{"two attackers", "k7/8/8/8/6nq/8/5PPP/6K1 w - - 0 1", 3*pawnShieldBonus - 14*14/4},
+	}
+	for _, tt := range tests {
+		sm := gameFromFEN(t, tt.fen).Position().Board().SquareMap()
+		whitePawns, blackPawns := pawnBitboards(sm)
+		if got := kingSafetyOf(newBoardState(sm), chess.White, whitePawns, blackPawns); got != tt.want {
+			t.Errorf("%s: kingSafetyOf = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMobility(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want int
+	}{
+		{"knight in the centre", "k7/8/8/8/3N4/8/8/7K w - - 0 1", 4 * mgMobilityWeights[chess.Knight]},
+		{"knight in the corner", "k7/8/8/8/8/8/8/N6K w - - 0 1", -2 * mgMobilityWeights[chess.Knight]},
+		// As casas ocupadas por peças da mesma cor não contam
+		{"blocked bishop", "k7/8/8/8/8/8/1P6/B6K w - - 0 1", -7 * mgMobilityWeights[chess.Bishop]},
+		{"black knight", "k7/8/8/8/3n4/8/8/7K w - - 0 1", -4 * mgMobilityWeights[chess.Knight]},
+	}
+	for _, tt := range tests {
+		sm := gameFromFEN(t, tt.fen).Position().Board().SquareMap()
+		if mg, _ := evaluateMobility(newBoardState(sm)); mg != tt.want {
+			t.Errorf("%s: evaluateMobility = %d, want %d", tt.name, mg, tt.want)
+		}
+	}
+}
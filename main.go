@@ -1,11 +1,15 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"math/rand"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/notnil/chess"
@@ -18,177 +22,403 @@ import (
 const (
 	AISIDE             = "aiside"
 	AGAINST_RANDOM_CPU = "againstRandomCPU"
+	MOVETIME           = "movetime"
+	DEPTH              = "depth"
+	HASH               = "hash"
+	CONTEMPT           = "contempt"
+	EVAL               = "eval"
+	UCI                = "uci"
+	XBOARD             = "xboard"
+	OPPONENT_ENGINE    = "opponentEngine"
+	OPPONENT_OPTIONS   = "opponentOptions"
+	OPPONENT_MOVETIME  = "opponentMovetime"
+	WHITE              = "white"
+	BLACK              = "black"
+	GAMES              = "games"
+	PLAYER1            = "player1"
+	PLAYER2            = "player2"
+	OPENINGS           = "openings"
+	PGN_OUT            = "pgnOut"
+	MAX_GAMES          = "maxGames"
+	ELO0               = "elo0"
+	ELO1               = "elo1"
+	ALPHA              = "alpha"
+	BETA               = "beta"
+	CONCURRENCY        = "concurrency"
+	SEED               = "seed"
+	PLAYERS            = "players"
+	FORMAT             = "format"
+	PAIRING_GAMES      = "pairingGames"
+	FEN                = "fen"
+	DIVIDE             = "divide"
+	VERIFY             = "verify"
+	SUITE              = "suite"
+	PGN                = "pgn"
+	SAVE_FILE          = "saveFile"
+	RESUME             = "resume"
+	TC                 = "tc"
 )
 
 var randomizer *rand.Rand
 
+// boardFlipped indica que o tabuleiro deve ser exibido do ponto de vista das
+// peças pretas
+var boardFlipped bool
+
+// boardClock é o relógio da partida exibido junto com o tabuleiro, quando
+// há controle de tempo
+var boardClock *Clock
+
+// aiEngine é o motor de busca utilizado pela IA, mantido entre as jogadas
+// para que a tabela de transposição seja aproveitada durante toda a partida
+var aiEngine *Engine
+
 func init() {
 	// Registro dos possíveis argumentos de linha de comando aceitos pelo programa,
 	// seus valores padrão e uma breve descrição sobre o que cada um faz
 	flag.String(AISIDE, "white", "which side of the game the AI will play")
 	flag.Bool(AGAINST_RANDOM_CPU, false, "set to true in order for the AI to play against an automated player choosing random moves")
+	flag.Duration(MOVETIME, 5*time.Second, "maximum time the AI may spend searching for a move, 0 for no limit")
+	flag.Int(DEPTH, 0, "maximum depth, in plies, the AI search may reach, 0 for no limit, or the depth of the perft command")
+	flag.Int(HASH, defaultHashMB, "size of the AI transposition table in MB")
+	flag.Int(CONTEMPT, 0, "how much worse than an even position the AI considers a draw")
+	flag.String(EVAL, MaterialEvaluatorName, "evaluator used by the AI, either material or positional")
+	flag.Bool(UCI, false, "set to true in order to speak the Universal Chess Interface protocol over stdin/stdout")
+	flag.Bool(XBOARD, false, "set to true in order to speak the XBoard/CECP protocol over stdin/stdout")
+	flag.String(OPPONENT_ENGINE, "", "path to an external UCI engine binary used by the engine player, which plays against the AI by default")
+	flag.String(OPPONENT_OPTIONS, "", "UCI options for the external engine, like 'Hash=32,Threads=1'")
+	flag.Duration(OPPONENT_MOVETIME, time.Second, "time the external engine may spend on each move")
+	flag.String(WHITE, "", "who plays the white pieces: ai, human, random or engine, optionally followed by options like 'ai:eval=positional,depth=4' or 'engine:/usr/bin/stockfish'")
+	flag.String(BLACK, "", "who plays the black pieces, in the same format as --white")
+	flag.Int(GAMES, 10, "number of games played by the match command")
+	flag.String(PLAYER1, AIPlayerKind, "first player of the match command, in the same format as --white")
+	flag.String(PLAYER2, AIPlayerKind, "second player of the match command, in the same format as --white")
+	flag.String(OPENINGS, "", "file with the openings the match games start from, either FEN/EPD lines or a .pgn file")
+	flag.String(PGN_OUT, "", "file where the PGN of every match game is written, instead of the standard output")
+	flag.Int(MAX_GAMES, 0, "maximum number of games played by the sprt command, 0 for no limit")
+	flag.Float64(ELO0, 0, "Elo gain of the sprt null hypothesis H0")
+	flag.Float64(ELO1, 5, "Elo gain of the sprt alternative hypothesis H1")
+	flag.Float64(ALPHA, 0.05, "probability of the sprt accepting H1 when H0 is true")
+	flag.Float64(BETA, 0.05, "probability of the sprt accepting H0 when H1 is true")
+	flag.Int(CONCURRENCY, 1, "number of games the match, sprt and tournament commands play at the same time")
+	flag.Int64(SEED, 0, "seed of the random players in matches, 0 for a different seed on every run")
+	flag.String(PLAYERS, "", "players of the tournament command separated by ';', like 'ai:eval=material;ai:eval=positional;random'")
+	flag.String(FORMAT, RoundRobinFormat, "format of the tournament command, either roundrobin or gauntlet, where the first player faces all the others")
+	flag.Int(PAIRING_GAMES, 2, "number of games of each pairing in the tournament command")
+	flag.String(FEN, "", "position, in FEN, the game starts from, also used by the perft command")
+	flag.String(PGN, "", "PGN file with a game to be continued from its last position")
+	flag.String(SAVE_FILE, "puc-chess.pgn", "file where the game is saved on exit and by the save command")
+	flag.Bool(RESUME, false, "set to true in order to continue the game saved in --saveFile")
+	flag.String(TC, "", "time control of the game, and of the match, sprt and tournament games, like '5' (5 minutes), '5+3' (3 seconds increment), '5d3' or '5b3' (3 seconds simple or Bronstein delay) or '40/90' (90 minutes every 40 moves)")
+	flag.Bool(DIVIDE, false, "set to true in order for the perft command to count the nodes of each root move separately")
+	flag.Bool(VERIFY, false, "set to true in order for the perft command to check the move generator against standard positions")
+	flag.String(SUITE, "", "EPD file with the test positions of the epd command")
 
-	// Interpretação dos argumentos de linha de comando informados
+	// Os argumentos são associados ao viper aqui, mas só são interpretados
+	// no main, para que os testes possam usar os seus valores padrão e
+	// interpretar os seus próprios argumentos
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
-	pflag.Parse()
 	viper.BindPFlags(pflag.CommandLine)
 
 	// Inicializa um randomizador utilizado para gerar jogas aleatórias no modo AGAINST_RANDOM_CPU
 	randSource := rand.NewSource(time.Now().UnixNano())
 	randomizer = rand.New(randSource)
+
+	// Inicializa o motor de busca da IA com os valores padrão
+	aiEngine = NewEngine(viper.GetInt(HASH))
 }
 
 func main() {
-	// Cria um novo tabuleiro com as peças nas posições iniciais
-	game := chess.NewGame()
-	PrintBoard(game)
+	// Interpretação dos argumentos de linha de comando informados
+	pflag.Parse()
 
-	// Continua o jogo até que ele acabe
-	for game.Outcome() == chess.NoOutcome {
-		// Trecho referente à vez da peça branca
-		fmt.Println("\n# White's turn")
-		// Verificando se a IA irá jogar do lado das peças brancas
-		if viper.GetString(AISIDE) == "white" {
-			// Faz a jogada utilizando a IA
-			if err := PlayAI(game); err != nil {
-				fmt.Println(err)
-				break
-			}
-		} else { // Caso contrário, se as peças brancas serão controladas pelo modo aleatório ou humano
-			// Faz a jogada utilizando o modo aleatório ou humano
-			if err := PlayRandomOrHuman(game); err != nil {
-				fmt.Println(err)
-				break
-			}
+	// Configura o motor de busca da IA com o tamanho de tabela de
+	// transposição e o contempt informados
+	aiEngine.SetHashSize(viper.GetInt(HASH))
+	aiEngine.Contempt = viper.GetInt(CONTEMPT)
+
+	// Seleciona o avaliador utilizado pela IA
+	evaluator, err := NewEvaluator(viper.GetString(EVAL))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	aiEngine.Evaluator = evaluator
+
+	// No modo UCI o jogo é controlado por uma interface gráfica externa
+	if viper.GetBool(UCI) {
+		if err := RunUCI(os.Stdin, os.Stdout, aiEngine); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	// O mesmo vale para o modo XBoard
+	if viper.GetBool(XBOARD) {
+		if err := RunXBoard(os.Stdin, os.Stdout, aiEngine); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
+		return
+	}
 
-		// Trecho referente à vez da peça preta
-		fmt.Println("\n# Black's turn")
-		// Verificando se a IA irá jogar do lado das peças pretas
-		if viper.GetString(AISIDE) == "black" {
-			// Faz a jogada utilizando a IA
-			if err := PlayAI(game); err != nil {
-				fmt.Println(err)
-				break
-			}
-		} else { // Caso contrário, se as peças brancas serão controladas pelo modo aleatório ou humano
-			// Faz a jogada utilizando o modo aleatório ou humano
-			if err := PlayRandomOrHuman(game); err != nil {
-				fmt.Println(err)
-				break
-			}
+	// Comandos informados como o primeiro argumento
+	switch pflag.Arg(0) {
+	case "":
+	case MatchCommand:
+		if err := RunMatchCommand(os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	case SPRTCommand:
+		if err := RunSPRTCommand(os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	case TournamentCommand:
+		if err := RunTournamentCommand(os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	case PerftCommand:
+		if err := RunPerftCommand(os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	case EPDCommand:
+		if err := RunEPDCommand(os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	default:
+		fmt.Printf("unknown command %q\n", pflag.Arg(0))
+		os.Exit(1)
+	}
+
+	// Cria o relógio quando a partida tem controle de tempo
+	timeControl, err := TimeControlFlag()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if timeControl != nil {
+		boardClock = NewClock(*timeControl)
+	}
+
+	// Cria os jogadores de cada lado do tabuleiro
+	white, err := NewPlayer(PlayerSpec(chess.White), randomizer)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer ClosePlayer(white)
+	black, err := NewPlayer(PlayerSpec(chess.Black), randomizer)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer ClosePlayer(black)
+
+	// Cria um novo tabuleiro com as peças nas posições iniciais, ou na
+	// posição informada por --fen ou --pgn, ou continua a partida salva
+	pgnPath := viper.GetString(PGN)
+	if viper.GetBool(RESUME) {
+		pgnPath = viper.GetString(SAVE_FILE)
+	}
+	game, err := LoadStartingGame(viper.GetString(FEN), pgnPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	c := newConsole(game, white, black, viper.GetString(SAVE_FILE), boardClock)
+	for _, player := range []Player{white, black} {
+		if human, ok := player.(*HumanPlayer); ok {
+			human.OnCommand = c.handle
+		}
+		if timed, ok := player.(TimedPlayer); ok && boardClock != nil {
+			timed.UseClock(boardClock)
+		}
+	}
+
+	// Salva a partida se o programa for interrompido ou o terminal for fechado
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		<-signals
+		fmt.Println()
+		if err := c.autosave(); err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Println("Game saved to", c.saveFile)
+		}
+		ClosePlayer(white)
+		ClosePlayer(black)
+		os.Exit(1)
+	}()
+
+	// Continua o jogo até que ele acabe
+	runner := &GameRunner{
+		White: white,
+		Black: black,
+		OnTurn: func(game *chess.Game, player Player) {
+			fmt.Printf("\n# %s's turn\n", game.Position().Turn().Name())
+			fmt.Printf("# %s player\n", player.Name())
+		},
+		OnMove: func(game *chess.Game, move *chess.Move) {
+			c.update()
+			fmt.Println("Selected move:", move.String())
+			PrintBoard(game)
+		},
+		Clock: boardClock,
+	}
+	PrintBoard(c.game.Game)
+	err = runner.NewGame()
+	for err == nil {
+		err = runner.Play(context.Background(), c.game.Game)
+		// Comandos como o undo alteram a partida e o load a troca por uma
+		// nova partida, e o jogo continua a partir da nova posição
+		if err == errGameLoaded {
+			err = runner.NewGame()
+		} else if err == errGameChanged {
+			err = nil
+		} else {
+			break
 		}
+		PrintBoard(c.game.Game)
+	}
+	if err == errQuitGame {
+		fmt.Println("The game was left unfinished, use --resume to continue it")
+	} else if err != nil {
+		fmt.Println(err)
 	}
 
 	// Após sair do loop acima o jogo terá terminado, então será exibido aqui o resultado final do jogo
-	fmt.Printf("The game finished. Outcome: %s. Method: %s.\n", game.Outcome(), game.Method())
-	fmt.Println("PGN:", game.String())
+	if c.game.Outcome() != chess.NoOutcome {
+		fmt.Printf("The game finished. Outcome: %s. Method: %s. %s.\n", c.game.Outcome(), describeMethod(c.game.Game), describeOutcome(c.game.Game))
+	}
+	c.update()
+	if err := c.autosave(); err != nil {
+		fmt.Println(err)
+	} else {
+		fmt.Println("Game saved to", c.saveFile)
+	}
+	c.game.AddTagPair("Result", c.game.Outcome().String())
+	fmt.Println("PGN:", c.game.String())
 }
 
-// PlayAI, dado um tabuleiro, faz uma jogada utilizando o algoritmo Alfa-Beta
-func PlayAI(game *chess.Game) error {
-	// Primeiro criamos um nó inicial para a nossa game tree
-	fmt.Println("# AI player")
-	gameTreeRootNode := NewGameTreeNode(game)
-	t1 := time.Now()
-	// Constrói uma game tree a partir do nó inicial
-	BuildGameTreeAt(gameTreeRootNode, 1)
-	fmt.Println("Time spent building game tree", time.Since(t1))
-	t2 := time.Now()
-	// Utiliza o algoritmo Alfa-Beta para identificar o melhor jogo
-	// dentre os que foram gerados na game tree
-	bestGame := AlphaBeta(gameTreeRootNode, 5, -1000000, 1000000, true)
-	fmt.Println("Time spent during AlphaBeta", time.Since(t2))
-	if bestGame == nil || bestGame.Game == nil {
-		return fmt.Errorf("it seems that there is no best game to choose")
-	}
-	// Extrai o histórico de jogadas do melhor jogo obtido pelo Alfa-Beta
-	moveHist := bestGame.Game.MoveHistory()
-	offset := 2
-	if viper.GetString(AISIDE) == "black" {
-		offset = 1
-	}
-	// Extrai a última jogada que deverá ser feita pela IA
-	aiMove := moveHist[len(moveHist)-offset].Move
-	// Executa a jogada no tabuleiro como a IA
-	game.Move(aiMove)
-	PrintBoard(game)
-	return nil
+// PlayerSpec retorna a descrição do jogador das peças da cor informada. Sem
+// as opções --white e --black, a IA joga do lado escolhido por --aiside e o
+// adversário é o motor externo, o jogador aleatório ou um humano. Quando só
+// o outro lado é informado, este lado fica com o papel oposto: o adversário
+// da IA ou a própria IA
+func PlayerSpec(c chess.Color) string {
+	side := strings.ToLower(c.Name())
+	if spec := viper.GetString(side); spec != "" {
+		return spec
+	}
+	if other := viper.GetString(strings.ToLower(c.Other().Name())); other != "" {
+		if kind, _, _ := strings.Cut(other, ":"); kind == AIPlayerKind {
+			return opponentSpec()
+		}
+		return AIPlayerKind
+	}
+	if viper.GetString(AISIDE) == side {
+		return AIPlayerKind
+	}
+	return opponentSpec()
 }
 
-// PlayRandomOrHuman, dado um tabuleiro, faz uma jogada que pode ser aleatória ou por um humano
-func PlayRandomOrHuman(game *chess.Game) error {
-	// Identifica se a jogada será feita de forma aleatória
+// opponentSpec retorna a descrição do adversário da IA: o motor externo, o
+// jogador aleatório ou um humano
+func opponentSpec() string {
+	if viper.GetString(OPPONENT_ENGINE) != "" {
+		return EnginePlayerKind
+	}
 	if viper.GetBool(AGAINST_RANDOM_CPU) {
-		fmt.Println("# Random player")
-		// Executa a jogada aleatória
-		if err := MoveRandom(game); err != nil {
-			return err
-		}
-	} else { // Caso contrário a jogada será com base na entrada de um humano
-		fmt.Println("# Human player")
-		for {
-			// Lê o movimento inserido pelo teclado
-			moveStr := ReadMove()
-			// Para jogadas mais rápidas, se o usuário digitar "r" iremos fazer uma jogada aleatória
-			if moveStr == "r" {
-				// Faz um movimento aleatório
-				if err := MoveRandom(game); err != nil {
-					fmt.Println(err)
-					continue
-				} else {
-					break
-				}
-			} else {
-				// Faz um movimento com base na jogada digitada pelo teclado
-				if err := game.MoveStr(moveStr); err != nil {
-					fmt.Printf("Invalid move provided, %s. It should be like, 'd3f5' or 'Qf5': %s\n", moveStr, err)
-					continue
-				}
-				break
-			}
-		}
+		return RandomPlayerKind
 	}
-	PrintBoard(game)
-	return nil
+	return HumanPlayerKind
 }
 
 // PrintBoard exibe o tabuleiro informado
 func PrintBoard(game *chess.Game) {
-	fmt.Println(game.Position().Board().Draw())
-	fmt.Println("Board evaluation: ", EvaluateStrongerSide(game))
+	fmt.Println(DrawBoard(game.Position().Board(), boardFlipped))
+	PrintEvaluation(game.Position())
 	fmt.Println("Current FEN:", game.FEN())
+	if boardClock != nil {
+		fmt.Println(boardClock)
+	}
+}
+
+// PrintEvaluation exibe a avaliação da posição informada
+func PrintEvaluation(pos *chess.Position) {
+	fmt.Println("Board evaluation: ", aiEngine.Evaluator.Evaluate(pos))
+	// Exibe cada termo da avaliação quando o avaliador permite
+	if explainer, ok := aiEngine.Evaluator.(Explainer); ok {
+		for _, term := range explainer.Explain(pos) {
+			fmt.Printf("  %s: %d\n", term.Name, term.Score)
+		}
+	}
+}
+
+// DrawBoard desenha o tabuleiro do ponto de vista das brancas ou, quando
+// invertido, do ponto de vista das pretas
+func DrawBoard(b *chess.Board, flipped bool) string {
+	if !flipped {
+		return b.Draw()
+	}
+	s := "\n H G F E D C B A\n"
+	for r := 0; r < 8; r++ {
+		s += chess.Rank(r).String()
+		for f := 7; f >= 0; f-- {
+			p := b.Piece(chess.NewSquare(chess.File(f), chess.Rank(r)))
+			if p == chess.NoPiece {
+				s += "-"
+			} else {
+				s += p.String()
+			}
+			s += " "
+		}
+		s += "\n"
+	}
+	return s
 }
 
-// ReadMove lê um movimento a partir do teclado
-func ReadMove() string {
-	fmt.Print("Enter the move > ")
-	scanner := bufio.NewScanner(os.Stdin)
-	if scanner.Scan() {
-		return scanner.Text()
+// FormatMoves converte uma sequência de jogadas para texto, separando-as
+// por espaços
+func FormatMoves(moves []*chess.Move) string {
+	strs := make([]string, len(moves))
+	for i, move := range moves {
+		strs[i] = move.String()
 	}
-	return ""
+	return strings.Join(strs, " ")
 }
 
-// MoveRandom faz um movimento aleatório no tabuleiro informado
-func MoveRandom(game *chess.Game) error {
-	// Identifica os movimentos válidos no tabuleiro no momento
-	moves := game.ValidMoves()
-	lenMoves := len(moves)
-	if len(moves) == 0 {
-		return fmt.Errorf("there are no valid moves left")
-	}
-	// Escolhe um movimento aleatório entre os válidos
-	move := moves[randomizer.Intn(lenMoves)]
-	fmt.Println("Selected random move:", move.String())
-	// Executa o movimento aleatório escolhido
-	game.Move(move)
-	return nil
+// FormatScore converte a avaliação de uma busca para texto, exibindo os
+// mates como o número de jogadas até eles
+func FormatScore(score int) string {
+	if score >= maxMateScore {
+		return fmt.Sprintf("mate in %d", (mateScore-score+1)/2)
+	} else if score <= -maxMateScore {
+		return fmt.Sprintf("mated in %d", (mateScore+score)/2)
+	}
+	return strconv.Itoa(score)
 }
 
 // EvaluateStrongerSide calcula qual lado do tabuleiro está ganhando
 func EvaluateStrongerSide(game *chess.Game) int {
-	sm := game.Position().Board().SquareMap()
+	return EvaluatePosition(game.Position())
+}
+
+// EvaluatePosition calcula qual lado está ganhando em uma posição, sem
+// depender do histórico de uma partida
+func EvaluatePosition(pos *chess.Position) int {
+	sm := pos.Board().SquareMap()
 	score := 0
 
 	for _, piece := range sm {
@@ -217,7 +447,8 @@ func EvaluateStrongerSide(game *chess.Game) int {
 	return score
 }
 
-// GameTreeNode representa um nó da game tree
+// GameTreeNode representa um nó da game tree. A busca não constrói mais a
+// árvore completa, mas ela continua disponível para depuração e visualização
 type GameTreeNode struct {
 	Game       *chess.Game
 	Evaluation int
@@ -299,27 +530,29 @@ func AlphaBeta(node *GameTreeNode, depth, a, b int, maximizingPlayer bool) *Game
 		return node
 	}
 
-	nodeA := &GameTreeNode{Evaluation: a}
-	nodeB := &GameTreeNode{Evaluation: b}
-
 	var value *GameTreeNode
 	if maximizingPlayer {
 		for _, child := range node.Children {
-			value2 := AlphaBeta(child, depth-1, a, b, false)
-			value = MaxNode(value, value2)
-			if value != nil && value.Evaluation >= b {
+			value = MaxNode(value, AlphaBeta(child, depth-1, a, b, false))
+			if value.Evaluation >= b {
 				break
 			}
-			nodeA = MaxNode(nodeA, value)
+			// Estreita a janela para os próximos filhos
+			if value.Evaluation > a {
+				a = value.Evaluation
+			}
 		}
 		return value
 	} else {
 		for _, child := range node.Children {
 			value = MinNode(value, AlphaBeta(child, depth-1, a, b, true))
-			if value != nil && value.Evaluation <= a {
+			if value.Evaluation <= a {
 				break
 			}
-			nodeB = MinNode(nodeB, value)
+			// Estreita a janela para os próximos filhos
+			if value.Evaluation < b {
+				b = value.Evaluation
+			}
 		}
 		return value
 	}
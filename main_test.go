@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/spf13/viper"
+)
+
+func TestPlayerSpec(t *testing.T) {
+	tests := []struct {
+		white, black, aiSide string
+		wantWhite, wantBlack string
+	}{
+		{"", "", "white", AIPlayerKind, HumanPlayerKind},
+		{"", "", "black", HumanPlayerKind, AIPlayerKind},
+		{"human", "", "white", HumanPlayerKind, AIPlayerKind},
+		{"", "ai", "white", HumanPlayerKind, AIPlayerKind},
+		{"ai:depth=2", "", "black", "ai:depth=2", HumanPlayerKind},
+		{"", "random", "black", AIPlayerKind, RandomPlayerKind},
+		{"random", "engine", "white", RandomPlayerKind, EnginePlayerKind},
+	}
+	defer func() {
+		viper.Set(WHITE, "")
+		viper.Set(BLACK, "")
+		viper.Set(AISIDE, "white")
+	}()
+	for _, tt := range tests {
+		viper.Set(WHITE, tt.white)
+		viper.Set(BLACK, tt.black)
+		viper.Set(AISIDE, tt.aiSide)
+		white, black := PlayerSpec(chess.White), PlayerSpec(chess.Black)
+		if white != tt.wantWhite || black != tt.wantBlack {
+			t.Errorf("--white %q --black %q --aiside %s: got %q vs %q, want %q vs %q",
+				tt.white, tt.black, tt.aiSide, white, black, tt.wantWhite, tt.wantBlack)
+		}
+	}
+}
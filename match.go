@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/spf13/viper"
+)
+
+// MatchCommand é o nome do comando que executa uma série de partidas entre
+// duas configurações de jogadores
+const MatchCommand = "match"
+
+// Match é uma série de partidas entre dois jogadores, que alternam as cores
+// a cada partida
+type Match struct {
+	Player1 PlayerFactory
+	Player2 PlayerFactory
+	// Games é o número de partidas a serem jogadas. Zero significa sem
+	// limite, até que ShouldStop interrompa a série
+	Games int
+	// Openings são as posições de onde as partidas começam. Cada abertura é
+	// jogada duas vezes, uma com cada jogador de brancas. Sem aberturas, as
+	// partidas começam da posição inicial
+	Openings []*chess.Game
+	// Event é o nome do evento registrado no PGN das partidas
+	Event string
+	// Concurrency é o número de partidas jogadas ao mesmo tempo
+	Concurrency int
+	// Seed é a semente dos números aleatórios usados pelos jogadores. Zero
+	// significa uma semente diferente a cada execução
+	Seed int64
+	// TimeControl, quando informado, é o controle de tempo das partidas
+	TimeControl *TimeControl
+	// OnGameEnd, quando informado, é chamado ao fim de cada partida com o
+	// placar do Player1 até então
+	OnGameEnd func(round int, game *chess.Game, score MatchScore)
+	// ShouldStop, quando informado, é consultado ao fim de cada partida e
+	// pode encerrar a série antes do número de partidas previsto
+	ShouldStop func(score MatchScore) bool
+}
+
+// Run joga todas as partidas e retorna o placar do Player1 e as partidas
+// jogadas, em ordem de rodada
+func (m *Match) Run(ctx context.Context) (MatchScore, []*chess.Game, error) {
+	seed := m.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	scheduler := &gameScheduler{
+		Factories:   []PlayerFactory{m.Player1, m.Player2},
+		Concurrency: m.Concurrency,
+		Seed:        seed,
+		Event:       m.Event,
+		TimeControl: m.TimeControl,
+	}
+
+	var score MatchScore
+	var played []playedGame
+	err := scheduler.Run(ctx, m.Games, m.schedule, func(r playedGame) bool {
+		// O Player1 é o jogador de índice 0
+		points := WhitePoints(r.Game.Outcome())
+		if r.White != 0 {
+			points = 1 - points
+		}
+		score.Add(points)
+		played = append(played, r)
+		if m.OnGameEnd != nil {
+			m.OnGameEnd(r.Round, r.Game, score)
+		}
+		return m.ShouldStop != nil && m.ShouldStop(score)
+	})
+
+	sort.Slice(played, func(i, j int) bool {
+		return played[i].Round < played[j].Round
+	})
+	games := make([]*chess.Game, len(played))
+	for i, r := range played {
+		games[i] = r.Game
+	}
+	return score, games, err
+}
+
+// schedule define as cores e a abertura de uma das partidas da série. As
+// cores são trocadas a cada partida, e cada par de partidas usa a mesma
+// abertura
+func (m *Match) schedule(round int) scheduledGame {
+	game := scheduledGame{Round: round, White: 0, Black: 1}
+	if round%2 == 0 {
+		game.White, game.Black = 1, 0
+	}
+	if len(m.Openings) > 0 {
+		game.Opening = m.Openings[((round-1)/2)%len(m.Openings)]
+	}
+	return game
+}
+
+// WhitePoints retorna os pontos obtidos pelas brancas em uma partida
+// terminada
+func WhitePoints(outcome chess.Outcome) float64 {
+	switch outcome {
+	case chess.WhiteWon:
+		return 1
+	case chess.BlackWon:
+		return 0
+	}
+	return 0.5
+}
+
+// StartingGame cria uma nova partida a partir de uma abertura, repetindo as
+// suas jogadas. Sem abertura, a partida começa da posição inicial
+func StartingGame(opening *chess.Game) (*chess.Game, error) {
+	if opening == nil {
+		return chess.NewGame(), nil
+	}
+	start := opening.Positions()[0].String()
+	if start == chess.StartingPosition().String() {
+		game := chess.NewGame()
+		return game, replayMoves(game, opening.Moves())
+	}
+	fen, err := chess.FEN(start)
+	if err != nil {
+		return nil, err
+	}
+	game := chess.NewGame(fen)
+	return game, replayMoves(game, opening.Moves())
+}
+
+// TagStartingPosition registra no PGN a posição de onde a partida começou,
+// quando ela não for a posição inicial
+func TagStartingPosition(game *chess.Game) {
+	if start := game.Positions()[0].String(); start != chess.StartingPosition().String() {
+		game.AddTagPair("SetUp", "1")
+		game.AddTagPair("FEN", start)
+	}
+}
+
+// replayMoves faz as jogadas informadas na partida
+func replayMoves(game *chess.Game, moves []*chess.Move) error {
+	for _, move := range moves {
+		if err := game.Move(move); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadOpenings lê as aberturas de um arquivo. Arquivos .pgn podem conter
+// várias partidas, e os demais arquivos devem ter uma posição FEN ou EPD
+// por linha
+func LoadOpenings(path string) ([]*chess.Game, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var openings []*chess.Game
+	if strings.EqualFold(filepath.Ext(path), ".pgn") {
+		scanner := chess.NewScanner(f)
+		for scanner.Scan() {
+			game := scanner.Next()
+			// O scanner devolve uma partida vazia ao chegar ao fim do arquivo
+			if len(game.Moves()) == 0 && game.GetTagPair("FEN") == nil {
+				continue
+			}
+			openings = append(openings, game)
+		}
+		if err := scanner.Err(); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read openings from %s: %w", path, err)
+		}
+	} else {
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			record, err := ParseEPD(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid opening at %s:%d: %w", path, line, err)
+			}
+			openings = append(openings, record.Game())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if len(openings) == 0 {
+		return nil, fmt.Errorf("no openings found in %s", path)
+	}
+	return openings, nil
+}
+
+// RunMatchCommand executa o comando match com os argumentos de linha de
+// comando, exibindo o resultado de cada partida e o placar final
+func RunMatchCommand(out io.Writer) error {
+	player1, player2 := viper.GetString(PLAYER1), viper.GetString(PLAYER2)
+	games := viper.GetInt(GAMES)
+	if games <= 0 {
+		return fmt.Errorf("the number of games should be positive")
+	}
+	timeControl, err := TimeControlFlag()
+	if err != nil {
+		return err
+	}
+	var openings []*chess.Game
+	if path := viper.GetString(OPENINGS); path != "" {
+		if openings, err = LoadOpenings(path); err != nil {
+			return err
+		}
+	}
+
+	// Sem um arquivo de saída, os PGNs são exibidos junto com os resultados
+	pgnOut := out
+	if path := viper.GetString(PGN_OUT); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		pgnOut = f
+	}
+
+	match := &Match{
+		Player1:     QuietPlayerFactory(player1),
+		Player2:     QuietPlayerFactory(player2),
+		Games:       games,
+		Openings:    openings,
+		Event:       "puc-chess match",
+		Concurrency: viper.GetInt(CONCURRENCY),
+		Seed:        viper.GetInt64(SEED),
+		TimeControl: timeControl,
+		OnGameEnd: func(round int, game *chess.Game, score MatchScore) {
+			fmt.Fprintf(out, "Game %d: %s - %s %s {%s}, score %s\n", round,
+				game.GetTagPair("White").Value, game.GetTagPair("Black").Value, game.Outcome(), describeOutcome(game), score)
+			fmt.Fprintf(pgnOut, "%s\n\n", game)
+		},
+	}
+	fmt.Fprintf(out, "Match: %s vs %s, %d games%s\n", player1, player2, match.Games, describeTimeControl(timeControl))
+	score, _, err := match.Run(context.Background())
+	printMatchScore(out, player1, player2, score)
+	return err
+}
+
+// describeTimeControl descreve o controle de tempo das partidas, quando houver
+func describeTimeControl(tc *TimeControl) string {
+	if tc == nil {
+		return ""
+	}
+	return ", time control " + tc.String()
+}
+
+// printMatchScore exibe o placar de uma série de partidas e a diferença de
+// Elo estimada entre os jogadores
+func printMatchScore(out io.Writer, player1, player2 string, score MatchScore) {
+	elo, margin := score.EloDifference()
+	fmt.Fprintf(out, "Score of %s vs %s: %s (%.1f/%d)\n", player1, player2, score, score.Points(), score.Games())
+	fmt.Fprintf(out, "Elo difference: %s\n", FormatElo(elo, margin))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"math/bits"
+
+	"github.com/notnil/chess"
+)
+
+// Pesos da mobilidade, em centipeões por casa, no meio-jogo e no final,
+// indexados pelo chess.PieceType. A mobilidade é medida em relação a um
+// número de casas considerado normal para cada peça, de forma que peças
+// presas sejam penalizadas e peças ativas bonificadas
+var (
+	mgMobilityWeights = [7]int{chess.Queen: 1, chess.Rook: 2, chess.Bishop: 5, chess.Knight: 4}
+	egMobilityWeights = [7]int{chess.Queen: 2, chess.Rook: 4, chess.Bishop: 5, chess.Knight: 4}
+	mobilityBaselines = [7]int{chess.Queen: 14, chess.Rook: 7, chess.Bishop: 7, chess.Knight: 4}
+)
+
+// evaluateMobility avalia a mobilidade das peças do ponto de vista das
+// peças brancas, contando as casas que cada cavalo, bispo, torre e dama
+// alcança sem considerar se a jogada deixaria o próprio rei em xeque
+func evaluateMobility(bs *boardState) (int, int) {
+	mg, eg := 0, 0
+	for sq := chess.Square(0); sq < 64; sq++ {
+		piece := bs.pieces[sq]
+		pt := piece.Type()
+		if pt == chess.NoPieceType || pt == chess.Pawn || pt == chess.King {
+			continue
+		}
+		own := bs.byColor[colorIndex(piece.Color())]
+		moves := bits.OnesCount64(bs.attacksFrom(sq)&^own) - mobilityBaselines[pt]
+		if piece.Color() == chess.White {
+			mg += moves * mgMobilityWeights[pt]
+			eg += moves * egMobilityWeights[pt]
+		} else {
+			mg -= moves * mgMobilityWeights[pt]
+			eg -= moves * egMobilityWeights[pt]
+		}
+	}
+	return mg, eg
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/notnil/chess"
+)
+
+// Faixas de pontuação usadas na ordenação, de forma que a jogada da tabela
+// de transposição seja sempre a primeira, seguida pelas capturas, pelas
+// killer moves e por último pelas jogadas calmas ordenadas pelo histórico
+const (
+	hashMoveScore   = 1 << 30
+	captureScore    = 1 << 24
+	killerMoveScore = 1 << 20
+	maxHistoryScore = killerMoveScore - 1
+)
+
+// moveKey identifica uma jogada independente da posição em que foi gerada
+type moveKey struct {
+	s1    chess.Square
+	s2    chess.Square
+	promo chess.PieceType
+}
+
+// keyOf retorna a chave da jogada informada
+func keyOf(move *chess.Move) moveKey {
+	return moveKey{s1: move.S1(), s2: move.S2(), promo: move.Promo()}
+}
+
+// moveOrdering guarda as informações aprendidas durante a busca para decidir
+// quais jogadas devem ser buscadas primeiro
+type moveOrdering struct {
+	// killers guarda, para cada ply, as duas últimas jogadas calmas que
+	// causaram um corte beta
+	killers [maxSearchDepth][2]moveKey
+	// history acumula, por cor, casa de origem e de destino, o quanto uma
+	// jogada calma causou cortes beta ao longo da busca
+	history [2][64][64]int
+}
+
+// newSearch prepara a ordenação para uma nova busca, descartando as killer
+// moves e reduzindo o peso do histórico das buscas anteriores
+func (o *moveOrdering) newSearch() {
+	o.killers = [maxSearchDepth][2]moveKey{}
+	for c := range o.history {
+		for s1 := range o.history[c] {
+			for s2 := range o.history[c][s1] {
+				o.history[c][s1][s2] /= 2
+			}
+		}
+	}
+}
+
+// clear descarta tudo o que foi aprendido pela ordenação
+func (o *moveOrdering) clear() {
+	*o = moveOrdering{}
+}
+
+// storeCutoff registra uma jogada calma que causou um corte beta
+func (o *moveOrdering) storeCutoff(pos *chess.Position, move *chess.Move, depth, ply int) {
+	if isNoisyMove(move) {
+		return
+	}
+	key := keyOf(move)
+	if ply < maxSearchDepth && o.killers[ply][0] != key {
+		o.killers[ply][1] = o.killers[ply][0]
+		o.killers[ply][0] = key
+	}
+	h := &o.history[colorIndex(pos.Turn())][move.S1()][move.S2()]
+	*h += depth * depth
+	if *h > maxHistoryScore {
+		*h = maxHistoryScore
+	}
+}
+
+// sortMoves ordena as jogadas: primeiro a jogada da tabela de transposição,
+// depois as capturas pelo critério MVV-LVA, as killer moves do ply e por
+// fim as jogadas calmas de acordo com o histórico
+func (o *moveOrdering) sortMoves(pos *chess.Position, moves []*chess.Move, hashMove *chess.Move, ply int) {
+	var hashKey moveKey
+	if hashMove != nil {
+		hashKey = keyOf(hashMove)
+	}
+	scores := make(map[*chess.Move]int, len(moves))
+	for _, move := range moves {
+		key := keyOf(move)
+		switch {
+		case hashMove != nil && key == hashKey:
+			scores[move] = hashMoveScore
+		case isNoisyMove(move):
+			scores[move] = captureScore + mvvLva(pos, move)
+		case ply < maxSearchDepth && key == o.killers[ply][0]:
+			scores[move] = killerMoveScore + 1
+		case ply < maxSearchDepth && key == o.killers[ply][1]:
+			scores[move] = killerMoveScore
+		default:
+			scores[move] = o.history[colorIndex(pos.Turn())][move.S1()][move.S2()]
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return scores[moves[i]] > scores[moves[j]]
+	})
+}
+
+// sortCaptures ordena as capturas e promoções pelo critério MVV-LVA
+func sortCaptures(pos *chess.Position, moves []*chess.Move) {
+	sort.SliceStable(moves, func(i, j int) bool {
+		return mvvLva(pos, moves[i]) > mvvLva(pos, moves[j])
+	})
+}
+
+// mvvLva pontua uma captura de forma que capturar a peça mais valiosa com a
+// peça menos valiosa seja tentado primeiro (Most Valuable Victim - Least
+// Valuable Attacker). Promoções recebem o valor da peça promovida
+func mvvLva(pos *chess.Position, move *chess.Move) int {
+	board := pos.Board()
+	victim := board.Piece(move.S2()).Type()
+	if move.HasTag(chess.EnPassant) {
+		victim = chess.Pawn
+	}
+	attacker := board.Piece(move.S1()).Type()
+	score := orderingValue(victim)*10 - orderingValue(attacker)
+	if move.Promo() != chess.NoPieceType {
+		score += orderingValue(move.Promo()) * 10
+	}
+	return score
+}
+
+// orderingValue retorna o valor relativo de um tipo de peça usado apenas
+// para ordenar as jogadas
+func orderingValue(pt chess.PieceType) int {
+	switch pt {
+	case chess.Pawn:
+		return 1
+	case chess.Knight:
+		return 2
+	case chess.Bishop:
+		return 3
+	case chess.Rook:
+		return 4
+	case chess.Queen:
+		return 5
+	case chess.King:
+		return 6
+	}
+	return 0
+}
+
+// colorIndex converte uma cor em um índice para as tabelas de histórico
+func colorIndex(c chess.Color) int {
+	if c == chess.Black {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// findMove procura a jogada informada, em notação UCI, na lista
+func findMove(t *testing.T, moves []*chess.Move, s string) *chess.Move {
+	t.Helper()
+	for _, move := range moves {
+		if move.String() == s {
+			return move
+		}
+	}
+	t.Fatalf("move %s not found", s)
+	return nil
+}
+
+func TestSortMoves(t *testing.T) {
+	fen := "4k3/8/8/3q4/4P3/8/8/3QK3 w - - 0 1"
+	pos := gameFromFEN(t, fen).Position()
+	moves := pos.ValidMoves()
+
+	// A jogada da tabela de transposição vem de outra posição igual, então
+	// ela precisa ser reconhecida pelas casas e não pelo ponteiro
+	hashMove := findMove(t, gameFromFEN(t, fen).Position().ValidMoves(), "e1f2")
+
+	var o moveOrdering
+	o.storeCutoff(pos, findMove(t, moves, "d1a4"), 3, 0)
+	o.storeCutoff(pos, findMove(t, moves, "d1h5"), 3, 5)
+	o.sortMoves(pos, moves, hashMove, 0)
+
+	// A jogada da tabela, as capturas pelo MVV-LVA, a killer move do ply e
+	// a jogada com histórico
+	want := []string{"e1f2", "e4d5", "d1d5", "d1a4", "d1h5"}
+	for i, s := range want {
+		if moves[i].String() != s {
+			t.Fatalf("moves are sorted as %v, want them to start with %v", moves, want)
+		}
+	}
+}
+
+func TestMoveToFront(t *testing.T) {
+	moves := chess.NewGame().ValidMoves()
+	before := append([]*chess.Move(nil), moves...)
+	moveToFront(moves, before[4])
+
+	if moves[0] != before[4] {
+		t.Fatalf("the first move is %s, want %s", moves[0], before[4])
+	}
+	rest := append(append([]*chess.Move(nil), before[:4]...), before[5:]...)
+	for i, move := range rest {
+		if moves[i+1] != move {
+			t.Fatalf("the other moves changed order: got %v, want %s followed by %v", moves, before[4], rest)
+		}
+	}
+}
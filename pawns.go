@@ -0,0 +1,186 @@
+package main
+
+import (
+	"math/bits"
+
+	"github.com/notnil/chess"
+)
+
+// Bônus de peão passado, em centipeões, indexados pela fileira do peão do
+// ponto de vista do seu dono. Quanto mais perto da promoção, maior o bônus
+var (
+	mgPassedPawnBonus = [8]int{0, 5, 10, 15, 25, 45, 70, 0}
+	egPassedPawnBonus = [8]int{0, 10, 20, 35, 60, 100, 150, 0}
+)
+
+// Penalidades, em centipeões, para as fraquezas da estrutura de peões
+const (
+	mgDoubledPawnPenalty  = 10
+	egDoubledPawnPenalty  = 20
+	mgIsolatedPawnPenalty = 10
+	egIsolatedPawnPenalty = 15
+	mgBackwardPawnPenalty = 8
+	egBackwardPawnPenalty = 10
+)
+
+// pawnCacheSize é o número de estruturas de peões guardadas pelo pawnCache.
+// Precisa ser uma potência de dois
+const pawnCacheSize = 1 << 14
+
+// pawnCacheEntry guarda a avaliação de uma estrutura de peões
+type pawnCacheEntry struct {
+	white, black uint64
+	mg, eg       int
+	valid        bool
+}
+
+// pawnCache guarda as avaliações das estruturas de peões já calculadas. Os
+// peões mudam muito menos que as demais peças durante a busca, então a mesma
+// estrutura é encontrada em muitos nós diferentes
+type pawnCache struct {
+	entries []pawnCacheEntry
+}
+
+// newPawnCache cria um cache de estruturas de peões vazio
+func newPawnCache() *pawnCache {
+	return &pawnCache{entries: make([]pawnCacheEntry, pawnCacheSize)}
+}
+
+// evaluate retorna a avaliação da estrutura de peões formada pelos
+// bitboards informados, calculando-a somente se ela não estiver guardada
+func (c *pawnCache) evaluate(white, black uint64) (int, int) {
+	index := ((white*0x9E3779B97F4A7C15 ^ black*0xC2B2AE3D27D4EB4F) >> 32) & (pawnCacheSize - 1)
+	entry := &c.entries[index]
+	if entry.valid && entry.white == white && entry.black == black {
+		return entry.mg, entry.eg
+	}
+	mg, eg := evaluatePawnStructure(white, black)
+	*entry = pawnCacheEntry{white: white, black: black, mg: mg, eg: eg, valid: true}
+	return mg, eg
+}
+
+// pawnBitboards retorna os bitboards dos peões brancos e pretos, em que cada
+// bit representa uma casa do tabuleiro, começando por a1
+func pawnBitboards(sm map[chess.Square]chess.Piece) (uint64, uint64) {
+	var white, black uint64
+	for sq, piece := range sm {
+		switch piece {
+		case chess.WhitePawn:
+			white |= 1 << uint(sq)
+		case chess.BlackPawn:
+			black |= 1 << uint(sq)
+		}
+	}
+	return white, black
+}
+
+// evaluatePawnStructure avalia a estrutura de peões do ponto de vista das
+// peças brancas, retornando as avaliações de meio-jogo e de final
+func evaluatePawnStructure(white, black uint64) (int, int) {
+	wmg, weg := evaluatePawnsOf(chess.White, white, black)
+	bmg, beg := evaluatePawnsOf(chess.Black, black, white)
+	return wmg - bmg, weg - beg
+}
+
+// evaluatePawnsOf avalia os peões de uma cor, dados os seus peões e os peões
+// do adversário, bonificando peões passados e penalizando peões dobrados,
+// isolados e atrasados
+func evaluatePawnsOf(c chess.Color, own, enemy uint64) (int, int) {
+	mg, eg := 0, 0
+	for file := 0; file < 8; file++ {
+		// Cada peão além do primeiro em uma mesma coluna é um peão dobrado
+		if count := bits.OnesCount64(own & fileMask(file)); count > 1 {
+			mg -= mgDoubledPawnPenalty * (count - 1)
+			eg -= egDoubledPawnPenalty * (count - 1)
+		}
+	}
+	for sq := 0; sq < 64; sq++ {
+		if own&(1<<uint(sq)) == 0 {
+			continue
+		}
+		file, rank := sq%8, sq/8
+		relativeRank := rank
+		if c == chess.Black {
+			relativeRank = 7 - rank
+		}
+
+		// Um peão é passado se nenhum peão adversário pode bloqueá-lo ou
+		// capturá-lo no caminho até a promoção
+		passed := enemy&aheadMask(c, rank)&(fileMask(file)|adjacentFilesMask(file)) == 0
+		if passed {
+			mg += mgPassedPawnBonus[relativeRank]
+			eg += egPassedPawnBonus[relativeRank]
+		}
+
+		// Um peão é isolado se não existem peões da mesma cor nas colunas
+		// vizinhas para protegê-lo
+		if own&adjacentFilesMask(file) == 0 {
+			mg -= mgIsolatedPawnPenalty
+			eg -= egIsolatedPawnPenalty
+			continue
+		}
+
+		// Um peão é atrasado se os peões das colunas vizinhas já avançaram
+		// além dele, de forma que nenhum pode protegê-lo, e a casa à sua
+		// frente é controlada por um peão adversário
+		support := own & adjacentFilesMask(file) &^ aheadMask(c, rank)
+		if !passed && support == 0 && stopSquareAttacked(c, file, rank, enemy) {
+			mg -= mgBackwardPawnPenalty
+			eg -= egBackwardPawnPenalty
+		}
+	}
+	return mg, eg
+}
+
+// stopSquareAttacked verifica se a casa à frente do peão é atacada por um
+// peão adversário
+func stopSquareAttacked(c chess.Color, file, rank int, enemy uint64) bool {
+	// Os peões adversários que atacam a casa à frente estão duas fileiras
+	// adiante, nas colunas vizinhas
+	attackerRank := rank + 2
+	if c == chess.Black {
+		attackerRank = rank - 2
+	}
+	if attackerRank < 0 || attackerRank > 7 {
+		return false
+	}
+	return enemy&adjacentFilesMask(file)&rankMask(attackerRank) != 0
+}
+
+// fileMask retorna o bitboard com todas as casas de uma coluna
+func fileMask(file int) uint64 {
+	return 0x0101010101010101 << uint(file)
+}
+
+// rankMask retorna o bitboard com todas as casas de uma fileira
+func rankMask(rank int) uint64 {
+	return 0xFF << uint(8*rank)
+}
+
+// adjacentFilesMask retorna o bitboard com as casas das colunas vizinhas
+func adjacentFilesMask(file int) uint64 {
+	var mask uint64
+	if file > 0 {
+		mask |= fileMask(file - 1)
+	}
+	if file < 7 {
+		mask |= fileMask(file + 1)
+	}
+	return mask
+}
+
+// aheadMask retorna o bitboard com as fileiras à frente da fileira
+// informada, do ponto de vista da cor informada
+func aheadMask(c chess.Color, rank int) uint64 {
+	var mask uint64
+	if c == chess.White {
+		for r := rank + 1; r < 8; r++ {
+			mask |= rankMask(r)
+		}
+	} else {
+		for r := rank - 1; r >= 0; r-- {
+			mask |= rankMask(r)
+		}
+	}
+	return mask
+}
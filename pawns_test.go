@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// bitboard retorna o bitboard com as casas informadas
+func bitboard(squares ...chess.Square) uint64 {
+	var bb uint64
+	for _, sq := range squares {
+		bb |= 1 << uint(sq)
+	}
+	return bb
+}
+
+func TestEvaluatePawnsOf(t *testing.T) {
+	tests := []struct {
+		name       string
+		color      chess.Color
+		own, enemy uint64
+		mg, eg     int
+	}{
+		{
+			"two passed pawns", chess.White,
+			bitboard(chess.D5, chess.E5), 0,
+			2 * mgPassedPawnBonus[4], 2 * egPassedPawnBonus[4],
+		},
+		{
+			// c7 pode capturar o peão de d5, mas não o de e5
+			"a pawn on an adjacent file stops the passed pawn", chess.White,
+			bitboard(chess.D5, chess.E5), bitboard(chess.C7),
+			mgPassedPawnBonus[4], egPassedPawnBonus[4],
+		},
+		{
+			// A fileira é contada do ponto de vista das pretas
+			"black passed pawns", chess.Black,
+			bitboard(chess.D4, chess.E4), 0,
+			2 * mgPassedPawnBonus[4], 2 * egPassedPawnBonus[4],
+		},
+		{
+			"doubled pawns", chess.White,
+			bitboard(chess.D2, chess.E2, chess.E3), bitboard(chess.D7, chess.E7),
+			-mgDoubledPawnPenalty, -egDoubledPawnPenalty,
+		},
+		{
+			"isolated pawns", chess.White,
+			bitboard(chess.A2, chess.C2), bitboard(chess.A7, chess.C7),
+			-2 * mgIsolatedPawnPenalty, -2 * egIsolatedPawnPenalty,
+		},
+		{
+			// O peão de c4 já avançou além de d2, e e4 controla d3
+			"backward pawn", chess.White,
+			bitboard(chess.D2, chess.C4), bitboard(chess.C6, chess.E4),
+			-mgBackwardPawnPenalty, -egBackwardPawnPenalty,
+		},
+		{
+			// Sem o peão de e4 a casa d3 está livre
+			"stop square not attacked", chess.White,
+			bitboard(chess.D2, chess.C4), bitboard(chess.C6),
+			0, 0,
+		},
+		{
+			// O peão de c2 ainda pode avançar para proteger d3
+			"pawn still supported", chess.White,
+			bitboard(chess.D2, chess.C2), bitboard(chess.C6, chess.E4),
+			0, 0,
+		},
+		{
+			"black backward pawn", chess.Black,
+			bitboard(chess.D7, chess.C5), bitboard(chess.C3, chess.E5),
+			-mgBackwardPawnPenalty, -egBackwardPawnPenalty,
+		},
+	}
+	for _, tt := range tests {
+		mg, eg := evaluatePawnsOf(tt.color, tt.own, tt.enemy)
+		if mg != tt.mg || eg != tt.eg {
+			t.Errorf("%s: evaluatePawnsOf = %d, %d, want %d, %d", tt.name, mg, eg, tt.mg, tt.eg)
+		}
+	}
+}
+
+func TestPawnCache(t *testing.T) {
+	white, black := bitboard(chess.D2, chess.C4), bitboard(chess.C6, chess.E4)
+	mg, eg := evaluatePawnStructure(white, black)
+
+	c := newPawnCache()
+	if gotMg, gotEg := c.evaluate(white, black); gotMg != mg || gotEg != eg {
+		t.Fatalf("evaluate = %d, %d, want %d, %d", gotMg, gotEg, mg, eg)
+	}
+
+	// Altera a entrada guardada para confirmar que a segunda consulta não
+	// calcula a avaliação de novo
+	var entry *pawnCacheEntry
+	for i := range c.entries {
+		if c.entries[i].valid {
+			entry = &c.entries[i]
+		}
+	}
+	if entry == nil || entry.white != white || entry.black != black {
+		t.Fatal("the pawn structure was not stored in the cache")
+	}
+	entry.mg, entry.eg = 1234, 5678
+	if gotMg, gotEg := c.evaluate(white, black); gotMg != 1234 || gotEg != 5678 {
+		t.Errorf("evaluate = %d, %d, want the cached 1234, 5678", gotMg, gotEg)
+	}
+
+	// Outra estrutura de peões não usa a entrada guardada
+	other := bitboard(chess.D2, chess.C2)
+	wantMg, wantEg := evaluatePawnStructure(other, black)
+	if gotMg, gotEg := c.evaluate(other, black); gotMg != wantMg || gotEg != wantEg {
+		t.Errorf("evaluate of another structure = %d, %d, want %d, %d", gotMg, gotEg, wantMg, wantEg)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/spf13/viper"
+)
+
+// PerftCommand é o nome do comando que conta as posições alcançáveis a
+// partir de uma posição, usado para verificar o gerador de jogadas
+const PerftCommand = "perft"
+
+// defaultPerftDepth é a profundidade usada quando --depth não é informado
+const defaultPerftDepth = 3
+
+// perftPosition é uma posição de referência com o número de folhas
+// esperado em cada profundidade, começando pela profundidade 1
+type perftPosition struct {
+	Name  string
+	FEN   string
+	Nodes []uint64
+}
+
+// perftPositions são as posições clássicas usadas para verificar geradores
+// de jogadas, que exercitam roques, capturas en passant, promoções e xeques
+var perftPositions = []perftPosition{
+	{"startpos", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		[]uint64{20, 400, 8902, 197281, 4865609}},
+	{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		[]uint64{48, 2039, 97862, 4085603}},
+	{"position3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		[]uint64{14, 191, 2812, 43238, 674624}},
+	{"position4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		[]uint64{6, 264, 9467, 422333}},
+	{"position5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		[]uint64{44, 1486, 62379, 2103487}},
+}
+
+// Perft conta as folhas da árvore de jogadas a partir da posição informada
+// até a profundidade informada
+func Perft(pos *chess.Position, depth int) uint64 {
+	if depth <= 0 {
+		return 1
+	}
+	moves := pos.ValidMoves()
+	// No último nível basta contar as jogadas, sem fazê-las
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+	var nodes uint64
+	for _, move := range moves {
+		nodes += Perft(pos.Update(move), depth-1)
+	}
+	return nodes
+}
+
+// PerftDivide conta as folhas separadamente para cada jogada da posição
+// informada, o que ajuda a encontrar em qual jogada um gerador diverge de
+// outro
+func PerftDivide(pos *chess.Position, depth int) map[string]uint64 {
+	divide := map[string]uint64{}
+	for _, move := range pos.ValidMoves() {
+		divide[move.String()] = Perft(pos.Update(move), depth-1)
+	}
+	return divide
+}
+
+// RunPerftCommand executa o comando perft com os argumentos de linha de
+// comando, contando as folhas da posição de --fen ou verificando as
+// posições de referência com --verify
+func RunPerftCommand(out io.Writer) error {
+	depth := viper.GetInt(DEPTH)
+	if depth <= 0 {
+		depth = defaultPerftDepth
+	}
+	if viper.GetBool(VERIFY) {
+		return verifyPerft(out, depth)
+	}
+
+	fen := viper.GetString(FEN)
+	if fen == "" {
+		fen = chess.StartingPosition().String()
+	}
+	option, err := chess.FEN(fen)
+	if err != nil {
+		return fmt.Errorf("invalid FEN %q: %w", fen, err)
+	}
+	pos := chess.NewGame(option).Position()
+	if err := ValidatePosition(pos); err != nil {
+		return fmt.Errorf("invalid FEN %q: %w", fen, err)
+	}
+
+	t1 := time.Now()
+	var nodes uint64
+	if viper.GetBool(DIVIDE) {
+		divide := PerftDivide(pos, depth)
+		moves := make([]string, 0, len(divide))
+		for move := range divide {
+			moves = append(moves, move)
+		}
+		sort.Strings(moves)
+		for _, move := range moves {
+			fmt.Fprintf(out, "%s: %d\n", move, divide[move])
+			nodes += divide[move]
+		}
+		fmt.Fprintf(out, "Moves: %d\n", len(moves))
+	} else {
+		nodes = Perft(pos, depth)
+	}
+	elapsed := time.Since(t1)
+	fmt.Fprintf(out, "Nodes: %d at depth %d in %s (%.0f nodes/s)\n", nodes, depth, elapsed, float64(nodes)/elapsed.Seconds())
+	return nil
+}
+
+// verifyPerft compara o número de folhas de cada posição de referência com
+// o esperado, até a profundidade informada
+func verifyPerft(out io.Writer, maxDepth int) error {
+	failures := 0
+	for _, p := range perftPositions {
+		failed := false
+		option, err := chess.FEN(p.FEN)
+		if err != nil {
+			return err
+		}
+		pos := chess.NewGame(option).Position()
+		for depth := 1; depth <= maxDepth && depth <= len(p.Nodes); depth++ {
+			t1 := time.Now()
+			nodes := Perft(pos, depth)
+			status := "ok"
+			if nodes != p.Nodes[depth-1] {
+				status = fmt.Sprintf("FAILED, expected %d", p.Nodes[depth-1])
+				failed = true
+			}
+			fmt.Fprintf(out, "%-10s depth %d: %d nodes in %s, %s\n", p.Name, depth, nodes, time.Since(t1), status)
+		}
+		if failed {
+			failures++
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("perft verification failed for %d positions", failures)
+	}
+	return nil
+}
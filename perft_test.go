@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// maxTestPerftNodes limita as profundidades das posições de referência
+// verificadas nos testes, que seriam lentos nas mais profundas
+const maxTestPerftNodes = 100000
+
+func TestPerft(t *testing.T) {
+	for _, p := range perftPositions {
+		option, err := chess.FEN(p.FEN)
+		if err != nil {
+			t.Fatalf("%s: %v", p.Name, err)
+		}
+		pos := chess.NewGame(option).Position()
+		for depth, want := range p.Nodes {
+			if want > maxTestPerftNodes {
+				break
+			}
+			if got := Perft(pos, depth+1); got != want {
+				t.Errorf("%s: Perft at depth %d = %d, want %d", p.Name, depth+1, got, want)
+			}
+		}
+	}
+}
+
+func TestPerftDivide(t *testing.T) {
+	divide := PerftDivide(chess.StartingPosition(), 3)
+	if len(divide) != 20 {
+		t.Errorf("PerftDivide returned %d moves, want 20", len(divide))
+	}
+	var total uint64
+	for _, nodes := range divide {
+		total += nodes
+	}
+	if total != 8902 {
+		t.Errorf("PerftDivide nodes add up to %d, want 8902", total)
+	}
+	if divide["e2e4"] != 600 {
+		t.Errorf("PerftDivide e2e4 = %d, want 600", divide["e2e4"])
+	}
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/spf13/viper"
+)
+
+// Tipos de jogador aceitos pelas opções --white e --black
+const (
+	AIPlayerKind     = "ai"
+	HumanPlayerKind  = "human"
+	RandomPlayerKind = "random"
+	EnginePlayerKind = "engine"
+)
+
+// Player é qualquer um que saiba escolher uma jogada para a posição atual de
+// uma partida: a IA, um humano, o jogador aleatório ou um motor externo
+type Player interface {
+	// Name é o nome do jogador, exibido durante a partida
+	Name() string
+	// ChooseMove escolhe uma das jogadas válidas da posição atual da
+	// partida, sem fazê-la
+	ChooseMove(ctx context.Context, game *chess.Game) (*chess.Move, error)
+}
+
+// GameStarter é implementado pelos jogadores que precisam ser avisados
+// quando uma nova partida começa
+type GameStarter interface {
+	NewGame() error
+}
+
+// consoleInput é a entrada do teclado, compartilhada por todos os jogadores
+// humanos para que nenhum deles perca o que já foi lido pelo outro
+var consoleInput = NewLineReader(os.Stdin)
+
+// LineReader lê as linhas de uma entrada, permitindo desistir da espera por
+// uma linha sem perdê-la: a leitura que ficou pendente é aproveitada pela
+// próxima chamada, em vez de disputar a entrada com uma nova leitura
+type LineReader struct {
+	scanner *bufio.Scanner
+	pending chan lineResult
+}
+
+// lineResult é o resultado da leitura de uma linha
+type lineResult struct {
+	line string
+	err  error
+}
+
+// NewLineReader cria um LineReader que lê da entrada informada
+func NewLineReader(r io.Reader) *LineReader {
+	return &LineReader{scanner: bufio.NewScanner(r)}
+}
+
+// ReadLine lê a próxima linha, desistindo quando o contexto for cancelado.
+// Nesse caso a leitura continua pendente em segundo plano
+func (r *LineReader) ReadLine(ctx context.Context) (string, error) {
+	if r.pending == nil {
+		results := make(chan lineResult, 1)
+		go func() {
+			results <- r.scanLine()
+		}()
+		r.pending = results
+	}
+	select {
+	case result := <-r.pending:
+		r.pending = nil
+		return result.line, result.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// scanLine lê a próxima linha da entrada
+func (r *LineReader) scanLine() lineResult {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return lineResult{err: err}
+		}
+		return lineResult{err: fmt.Errorf("there is no more input to read moves from")}
+	}
+	return lineResult{line: r.scanner.Text()}
+}
+
+// AIPlayer é o jogador controlado pela busca Alfa-Beta
+type AIPlayer struct {
+	// Label é o nome exibido da IA, útil para diferenciar IAs com
+	// configurações diferentes
+	Label  string
+	Engine *Engine
+	Limits SearchLimits
+	// Out, quando informado, recebe as estatísticas de cada busca
+	Out io.Writer
+	// Clock, quando informado, limita o tempo de cada busca ao tempo que a
+	// IA pode gastar na jogada
+	Clock *Clock
+}
+
+// Name retorna o nome da IA
+func (p *AIPlayer) Name() string {
+	if p.Label != "" {
+		return p.Label
+	}
+	return "puc-chess"
+}
+
+// NewGame limpa o que a IA aprendeu na partida anterior
+func (p *AIPlayer) NewGame() error {
+	p.Engine.NewGame()
+	return nil
+}
+
+// UseClock faz a IA administrar o tempo do relógio informado
+func (p *AIPlayer) UseClock(clock *Clock) {
+	p.Clock = clock
+}
+
+// SearchLimits retorna os limites da busca da IA em uma jogada do lado
+// informado, que não passa do tempo que ela pode gastar no relógio
+func (p *AIPlayer) SearchLimits(color chess.Color) SearchLimits {
+	limits := p.Limits
+	if p.Clock != nil {
+		if budget := p.Clock.MoveBudget(color); limits.MoveTime == 0 || budget < limits.MoveTime {
+			limits.MoveTime = budget
+		}
+	}
+	return limits
+}
+
+// ChooseMove busca a melhor jogada aprofundando a busca até que o tempo ou a
+// profundidade máxima configurados sejam atingidos
+func (p *AIPlayer) ChooseMove(ctx context.Context, game *chess.Game) (*chess.Move, error) {
+	t1 := time.Now()
+	result, err := p.Engine.IterativeDeepening(ctx, game, p.SearchLimits(game.Position().Turn()))
+	if err != nil {
+		return nil, err
+	}
+	if result == nil || result.Move == nil {
+		return nil, fmt.Errorf("it seems that there is no best game to choose")
+	}
+	if p.Out != nil {
+		elapsed := time.Since(t1)
+		fmt.Fprintf(p.Out, "Search reached depth %d with score %s in %s\n", result.Depth, FormatScore(result.Score), elapsed)
+		fmt.Fprintf(p.Out, "Nodes searched: %d (%.0f nodes/s)\n", result.Nodes, float64(result.Nodes)/elapsed.Seconds())
+		fmt.Fprintln(p.Out, "Principal variation:", FormatMoves(result.PV))
+	}
+	return result.Move, nil
+}
+
+// HumanPlayer é o jogador que digita as suas jogadas no teclado
+type HumanPlayer struct {
+	In  *LineReader
+	Out io.Writer
+	// Rand é usado quando o humano pede uma jogada aleatória
+	Rand *rand.Rand
+	// OnCommand, quando informado, recebe as linhas que não são jogadas,
+	// retornando true quando a linha for um comando conhecido. Um erro
+	// retornado por ele interrompe a escolha da jogada
+	OnCommand func(game *chess.Game, line string) (bool, error)
+}
+
+// Name retorna o nome do jogador humano
+func (p *HumanPlayer) Name() string {
+	return "Human"
+}
+
+// ChooseMove lê jogadas do teclado até que uma jogada válida seja digitada
+func (p *HumanPlayer) ChooseMove(ctx context.Context, game *chess.Game) (*chess.Move, error) {
+	for {
+		fmt.Fprint(p.Out, "Enter the move > ")
+		line, err := p.readLine(ctx)
+		if err != nil {
+			return nil, err
+		}
+		moveStr := strings.TrimSpace(line)
+		// Para jogadas mais rápidas, se o usuário digitar "r" iremos fazer uma jogada aleatória
+		if moveStr == "r" {
+			return randomMove(game, p.Rand)
+		}
+		if p.OnCommand != nil {
+			handled, err := p.OnCommand(game, moveStr)
+			if err != nil {
+				return nil, err
+			}
+			if handled {
+				continue
+			}
+		}
+		move, err := chess.AlgebraicNotation{}.Decode(game.Position(), moveStr)
+		if err != nil {
+			// Também aceita jogadas na notação UCI, como "e2e4"
+			var uciErr error
+			if move, uciErr = (chess.UCINotation{}).Decode(game.Position(), moveStr); uciErr != nil {
+				fmt.Fprintf(p.Out, "Invalid move provided, %s. It should be like, 'd3f5' or 'Qf5', or type 'help': %s\n", moveStr, err)
+				continue
+			}
+		}
+		if legal := findValidMove(game, move); legal != nil {
+			return legal, nil
+		}
+		fmt.Fprintf(p.Out, "Invalid move provided, %s. It is not a legal move in this position\n", moveStr)
+	}
+}
+
+// readLine lê a próxima linha digitada, desistindo quando o contexto for
+// cancelado, como quando o tempo do relógio acaba
+func (p *HumanPlayer) readLine(ctx context.Context) (string, error) {
+	line, err := p.In.ReadLine(ctx)
+	if err != nil && ctx.Err() != nil {
+		fmt.Fprintln(p.Out)
+	}
+	return line, err
+}
+
+// RandomPlayer é o jogador automático que escolhe jogadas aleatórias
+type RandomPlayer struct {
+	Rand *rand.Rand
+}
+
+// Name retorna o nome do jogador aleatório
+func (p *RandomPlayer) Name() string {
+	return "Random"
+}
+
+// ChooseMove escolhe uma jogada aleatória entre as válidas
+func (p *RandomPlayer) ChooseMove(ctx context.Context, game *chess.Game) (*chess.Move, error) {
+	return randomMove(game, p.Rand)
+}
+
+// UCIEnginePlayer é o jogador controlado por um motor externo
+type UCIEnginePlayer struct {
+	Engine   *UCIEngine
+	MoveTime time.Duration
+	// Clock, quando informado, limita o tempo de cada jogada ao tempo que o
+	// motor pode gastar
+	Clock *Clock
+}
+
+// Name retorna o nome informado pelo motor
+func (p *UCIEnginePlayer) Name() string {
+	return p.Engine.Name
+}
+
+// NewGame avisa o motor de que uma nova partida vai começar
+func (p *UCIEnginePlayer) NewGame() error {
+	return p.Engine.NewGame()
+}
+
+// UseClock faz o motor jogar dentro do tempo do relógio informado
+func (p *UCIEnginePlayer) UseClock(clock *Clock) {
+	p.Clock = clock
+}
+
+// ChooseMove pede ao motor a melhor jogada para a posição atual
+func (p *UCIEnginePlayer) ChooseMove(ctx context.Context, game *chess.Game) (*chess.Move, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	moveTime := p.MoveTime
+	if p.Clock != nil {
+		if budget := p.Clock.MoveBudget(game.Position().Turn()); budget < moveTime {
+			moveTime = budget
+		}
+	}
+	return p.Engine.BestMove(game, moveTime)
+}
+
+// Close encerra o processo do motor
+func (p *UCIEnginePlayer) Close() error {
+	return p.Engine.Close()
+}
+
+// NewPlayer cria um jogador a partir da sua descrição, no formato
+// "tipo[:opções]", usando o gerador de números aleatórios informado. A IA
+// aceita opções como "ai:eval=positional,depth=4", que substituem os
+// argumentos de linha de comando, e o motor externo aceita o caminho do
+// executável, como "engine:/usr/bin/stockfish"
+func NewPlayer(spec string, r *rand.Rand) (Player, error) {
+	kind, options, _ := strings.Cut(spec, ":")
+	switch kind {
+	case AIPlayerKind:
+		return newAIPlayer(options)
+	case HumanPlayerKind:
+		return &HumanPlayer{In: consoleInput, Out: os.Stdout, Rand: r}, nil
+	case RandomPlayerKind:
+		return &RandomPlayer{Rand: r}, nil
+	case EnginePlayerKind:
+		path := options
+		if path == "" {
+			path = viper.GetString(OPPONENT_ENGINE)
+		}
+		if path == "" {
+			return nil, fmt.Errorf("missing the path of the uci engine, use 'engine:<path>' or --%s", OPPONENT_ENGINE)
+		}
+		engineOptions, err := ParseUCIEngineOptions(viper.GetString(OPPONENT_OPTIONS))
+		if err != nil {
+			return nil, err
+		}
+		engine, err := StartUCIEngine(path, engineOptions)
+		if err != nil {
+			return nil, err
+		}
+		return &UCIEnginePlayer{Engine: engine, MoveTime: viper.GetDuration(OPPONENT_MOVETIME)}, nil
+	}
+	return nil, fmt.Errorf("unknown player %q, it should be one of %s, %s, %s or %s",
+		kind, AIPlayerKind, HumanPlayerKind, RandomPlayerKind, EnginePlayerKind)
+}
+
+// NewQuietPlayer cria um jogador como o NewPlayer, mas sem exibir as
+// estatísticas da busca da IA, útil quando muitas partidas são jogadas
+func NewQuietPlayer(spec string, r *rand.Rand) (Player, error) {
+	player, err := NewPlayer(spec, r)
+	if ai, ok := player.(*AIPlayer); ok {
+		ai.Out = nil
+	}
+	return player, err
+}
+
+// newAIPlayer cria uma IA com a sua própria tabela de transposição, usando
+// os argumentos de linha de comando como padrão para as opções não informadas
+func newAIPlayer(spec string) (*AIPlayer, error) {
+	options, err := parseOptions(spec)
+	if err != nil {
+		return nil, err
+	}
+	hash, contempt := viper.GetInt(HASH), viper.GetInt(CONTEMPT)
+	eval := viper.GetString(EVAL)
+	limits := SearchLimits{Depth: viper.GetInt(DEPTH), MoveTime: viper.GetDuration(MOVETIME)}
+	for name, value := range options {
+		switch name {
+		case HASH:
+			hash, err = strconv.Atoi(value)
+		case CONTEMPT:
+			contempt, err = strconv.Atoi(value)
+		case EVAL:
+			eval = value
+		case DEPTH:
+			limits.Depth, err = strconv.Atoi(value)
+		case MOVETIME:
+			limits.MoveTime, err = time.ParseDuration(value)
+		default:
+			return nil, fmt.Errorf("unknown ai option %q", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for the ai option %s", value, name)
+		}
+	}
+	evaluator, err := NewEvaluator(eval)
+	if err != nil {
+		return nil, err
+	}
+	engine := NewEngine(hash)
+	engine.Contempt = contempt
+	engine.Evaluator = evaluator
+	label := "puc-chess"
+	if spec != "" {
+		label += " (" + spec + ")"
+	}
+	return &AIPlayer{Label: label, Engine: engine, Limits: limits, Out: os.Stdout}, nil
+}
+
+// ClosePlayer libera os recursos do jogador, como o processo de um motor
+// externo, quando ele tiver algum
+func ClosePlayer(p Player) error {
+	if closer, ok := p.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// randomMove escolhe uma jogada aleatória entre as válidas
+func randomMove(game *chess.Game, r *rand.Rand) (*chess.Move, error) {
+	moves := game.ValidMoves()
+	if len(moves) == 0 {
+		return nil, fmt.Errorf("there are no valid moves left")
+	}
+	return moves[r.Intn(len(moves))], nil
+}
+
+// findValidMove procura, entre as jogadas válidas da partida, a jogada com
+// as mesmas casas de origem e destino e a mesma promoção
+func findValidMove(game *chess.Game, move *chess.Move) *chess.Move {
+	for _, valid := range game.ValidMoves() {
+		if valid.S1() == move.S1() && valid.S2() == move.S2() && valid.Promo() == move.Promo() {
+			return valid
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestLineReaderKeepsPendingLine(t *testing.T) {
+	in, w := io.Pipe()
+	reader := NewLineReader(in)
+
+	// A espera é abandonada antes de a linha chegar, como quando o tempo do
+	// relógio acaba
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := reader.ReadLine(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("ReadLine error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// A linha digitada depois é entregue à próxima leitura, e não perdida
+	// em uma leitura abandonada
+	go func() {
+		io.WriteString(w, "y\ne4\n")
+		w.Close()
+	}()
+	for _, want := range []string{"y", "e4"} {
+		line, err := reader.ReadLine(context.Background())
+		if err != nil || line != want {
+			t.Fatalf("ReadLine = %q, %v, want %q", line, err, want)
+		}
+	}
+	if _, err := reader.ReadLine(context.Background()); err == nil {
+		t.Error("ReadLine should fail at the end of the input")
+	}
+}
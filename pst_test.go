@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// mirrorFEN espelha a posição verticalmente e troca as cores das peças, de
+// forma que a posição resultante seja a mesma com os lados invertidos
+func mirrorFEN(fen string) string {
+	fields := strings.Fields(fen)
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	fields[0] = swapCase(strings.Join(ranks, "/"))
+	if fields[1] == "w" {
+		fields[1] = "b"
+	} else {
+		fields[1] = "w"
+	}
+	if fields[2] != "-" {
+		// As letras maiúsculas precisam continuar antes das minúsculas
+		castling := swapCase(fields[2])
+		fields[2] = strings.Map(keepUpper, castling) + strings.Map(keepLower, castling)
+	}
+	if fields[3] != "-" {
+		rank := fields[3][1]
+		fields[3] = fields[3][:1] + string('1'+'8'-rank)
+	}
+	return strings.Join(fields, " ")
+}
+
+// swapCase troca as letras maiúsculas por minúsculas e vice-versa
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return r
+	}, s)
+}
+
+// keepUpper e keepLower descartam as letras minúsculas e maiúsculas,
+// respectivamente, quando usadas com o strings.Map
+func keepUpper(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r
+	}
+	return -1
+}
+
+func keepLower(r rune) rune {
+	if r >= 'a' && r <= 'z' {
+		return r
+	}
+	return -1
+}
+
+// evaluationPositions são posições sem simetria entre os lados, usadas para
+// verificar que a avaliação não favorece nenhuma das cores
+var evaluationPositions = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"rnbqkb1r/pp2pppp/3p1n2/8/3NP3/8/PPP2PPP/RNBQKB1R w KQkq - 1 5",
+	"r1bq1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N1PN2/PP3PPP/R2QKB1R w KQ - 0 9",
+	"2r3k1/1p3ppp/p2p4/3Pp3/4P1n1/2N2P2/PP4PP/2R3K1 b - - 0 24",
+	"8/5pk1/6p1/3P4/1p3P2/8/6PP/6K1 w - - 0 40",
+	"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3",
+}
+
+func TestMirrorFEN(t *testing.T) {
+	got := mirrorFEN("rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b Kq e3 0 3")
+	want := "rnbqkbnr/pppp1ppp/8/3Pp3/8/8/PPP1PPPP/RNBQKBNR w Qk e6 0 3"
+	if got != want {
+		t.Errorf("mirrorFEN = %q, want %q", got, want)
+	}
+}
+
+func TestPositionalEvaluatorSymmetry(t *testing.T) {
+	pe := NewPositionalEvaluator()
+	for _, fen := range evaluationPositions {
+		terms := pe.terms(gameFromFEN(t, fen).Position())
+		mirrored := pe.terms(gameFromFEN(t, mirrorFEN(fen)).Position())
+		for i := range terms {
+			if terms[i] != -mirrored[i] {
+				t.Errorf("%s: %s is %d, but %d in the mirrored position",
+					fen, positionalTermNames[i], terms[i], mirrored[i])
+			}
+		}
+	}
+}
+
+func TestPSTIndex(t *testing.T) {
+	// As tabelas estão escritas com a oitava fileira primeiro, do ponto de
+	// vista das brancas
+	tests := []struct {
+		sq    chess.Square
+		color chess.Color
+		want  int
+	}{
+		{chess.A8, chess.White, 0},
+		{chess.A1, chess.White, 56},
+		{chess.E2, chess.White, 52},
+		{chess.A1, chess.Black, 0},
+		{chess.E7, chess.Black, 52},
+		{chess.H8, chess.Black, 63},
+	}
+	for _, tt := range tests {
+		if got := pstIndex(tt.sq, tt.color); got != tt.want {
+			t.Errorf("pstIndex(%s, %s) = %d, want %d", tt.sq, tt.color.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestTaper(t *testing.T) {
+	tests := []struct {
+		mg, eg, phase, want int
+	}{
+		{100, 40, maxGamePhase, 100},
+		{100, 40, 0, 40},
+		{100, 40, maxGamePhase / 2, 70},
+		// Promoções podem deixar a fase acima do máximo
+		{100, 40, maxGamePhase + 4, 100},
+	}
+	for _, tt := range tests {
+		if got := taper(tt.mg, tt.eg, tt.phase); got != tt.want {
+			t.Errorf("taper(%d, %d, %d) = %d, want %d", tt.mg, tt.eg, tt.phase, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/notnil/chess"
+)
+
+// GameRunner conduz uma partida entre dois jogadores, pedindo a jogada de
+// quem estiver na vez até que a partida termine
+type GameRunner struct {
+	White Player
+	Black Player
+	// OnTurn, quando informado, é chamado antes de pedir a jogada de cada
+	// jogador
+	OnTurn func(game *chess.Game, player Player)
+	// OnMove, quando informado, é chamado depois de cada jogada feita
+	OnMove func(game *chess.Game, move *chess.Move)
+	// Clock, quando informado, é o relógio da partida. O lado cujo tempo
+	// acabar perde a partida
+	Clock *Clock
+}
+
+// PlayerFor retorna o jogador que controla as peças da cor informada
+func (r *GameRunner) PlayerFor(c chess.Color) Player {
+	if c == chess.Black {
+		return r.Black
+	}
+	return r.White
+}
+
+// NewGame avisa os jogadores do início de uma nova partida
+func (r *GameRunner) NewGame() error {
+	for _, player := range []Player{r.White, r.Black} {
+		if starter, ok := player.(GameStarter); ok {
+			if err := starter.NewGame(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Play conduz a partida até o fim, a partir da posição atual do tabuleiro
+// informado. Os jogadores não são avisados do início da partida, já que ela
+// pode estar sendo retomada, então o NewGame deve ser chamado antes quando a
+// partida for nova
+func (r *GameRunner) Play(ctx context.Context, game *chess.Game) error {
+	for game.Outcome() == chess.NoOutcome {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		player := r.PlayerFor(game.Position().Turn())
+		if r.OnTurn != nil {
+			r.OnTurn(game, player)
+		}
+		move, err := r.chooseMove(ctx, game, player)
+		if err == errTimeForfeit {
+			LoseOnTime(game, game.Position().Turn())
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := game.Move(move); err != nil {
+			return err
+		}
+		if r.OnMove != nil {
+			r.OnMove(game, move)
+		}
+	}
+	return nil
+}
+
+// errTimeForfeit indica que o tempo do jogador acabou antes da jogada
+var errTimeForfeit = errors.New("the player ran out of time")
+
+// chooseMove pede a jogada do jogador, limitando o tempo de espera ao tempo
+// restante no relógio, quando houver
+func (r *GameRunner) chooseMove(ctx context.Context, game *chess.Game, player Player) (*chess.Move, error) {
+	if r.Clock == nil {
+		return player.ChooseMove(ctx, game)
+	}
+	r.Clock.Start(game.Position().Turn())
+	turnCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go r.Clock.Watch(turnCtx, cancel)
+	move, err := player.ChooseMove(turnCtx, game)
+	inTime := r.Clock.Stop(err == nil)
+	// Sem que a partida tenha sido interrompida, a jogada só é cancelada
+	// quando o tempo acaba
+	if ctx.Err() == nil && (!inTime || turnCtx.Err() != nil) {
+		return nil, errTimeForfeit
+	}
+	return move, err
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// countingPlayer joga a primeira jogada válida e conta quantas vezes foi
+// avisado do início de uma partida
+type countingPlayer struct {
+	newGames int
+}
+
+func (p *countingPlayer) Name() string {
+	return "Counting"
+}
+
+func (p *countingPlayer) NewGame() error {
+	p.newGames++
+	return nil
+}
+
+func (p *countingPlayer) ChooseMove(ctx context.Context, game *chess.Game) (*chess.Move, error) {
+	return game.ValidMoves()[0], nil
+}
+
+func TestGameRunnerNewGame(t *testing.T) {
+	white, black := &countingPlayer{}, &countingPlayer{}
+	runner := &GameRunner{White: white, Black: black}
+	if err := runner.NewGame(); err != nil {
+		t.Fatal(err)
+	}
+	// Uma partida retomada, como depois de um undo, não é uma nova partida
+	game := chess.NewGame()
+	for i := 0; i < 2; i++ {
+		if err := runner.Play(context.Background(), game); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if game.Outcome() == chess.NoOutcome {
+		t.Error("the game should have been played to the end")
+	}
+	if white.newGames != 1 || black.newGames != 1 {
+		t.Errorf("the players were told of %d and %d new games, want 1", white.newGames, black.newGames)
+	}
+}
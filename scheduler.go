@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// PlayerFactory cria uma nova instância de um jogador. Partidas jogadas ao
+// mesmo tempo não podem compartilhar jogadores, já que a IA e os motores
+// externos guardam o estado da partida, então cada worker cria os seus
+type PlayerFactory func(r *rand.Rand) (Player, error)
+
+// QuietPlayerFactory retorna uma PlayerFactory que cria jogadores a partir
+// da sua descrição, como o NewQuietPlayer
+func QuietPlayerFactory(spec string) PlayerFactory {
+	return func(r *rand.Rand) (Player, error) {
+		return NewQuietPlayer(spec, r)
+	}
+}
+
+// scheduledGame é uma partida a ser jogada, em que White e Black são os
+// índices dos jogadores no gameScheduler
+type scheduledGame struct {
+	Round   int
+	White   int
+	Black   int
+	Opening *chess.Game
+}
+
+// playedGame é o resultado de uma partida jogada por um dos workers
+type playedGame struct {
+	scheduledGame
+	Game *chess.Game
+	Err  error
+}
+
+// gameScheduler joga partidas entre vários jogadores, com várias partidas
+// sendo jogadas ao mesmo tempo
+type gameScheduler struct {
+	Factories []PlayerFactory
+	// Concurrency é o número de partidas jogadas ao mesmo tempo
+	Concurrency int
+	// Seed é a semente dos números aleatórios. Cada partida usa a semente
+	// somada ao número da rodada, de forma que o resultado não dependa de
+	// qual worker jogou a partida
+	Seed  int64
+	Event string
+	// TimeControl, quando informado, é o controle de tempo das partidas,
+	// cada uma com o seu próprio relógio
+	TimeControl *TimeControl
+}
+
+// Run joga as partidas retornadas por schedule, da rodada 1 até total, ou
+// sem limite se total for zero. onResult é chamado na goroutine de quem
+// chamou Run, uma partida por vez e na ordem em que elas terminarem, e pode
+// encerrar as partidas restantes retornando true
+func (s *gameScheduler) Run(ctx context.Context, total int, schedule func(round int) scheduledGame, onResult func(playedGame) bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan scheduledGame)
+	go func() {
+		defer close(jobs)
+		for round := 1; total == 0 || round <= total; round++ {
+			select {
+			case jobs <- schedule(round):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	workers := s.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	results := make(chan playedGame)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx, jobs, results)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Os resultados são recebidos até que todos os workers terminem, mesmo
+	// depois de um erro, para que nenhum deles fique bloqueado
+	var err error
+	for r := range results {
+		if err != nil || ctx.Err() != nil {
+			continue
+		}
+		if r.Err != nil {
+			err = fmt.Errorf("game %d: %w", r.Round, r.Err)
+			cancel()
+			continue
+		}
+		if onResult(r) {
+			cancel()
+		}
+	}
+	return err
+}
+
+// work joga as partidas recebidas até que elas acabem, com as suas próprias
+// instâncias dos jogadores
+func (s *gameScheduler) work(ctx context.Context, jobs <-chan scheduledGame, results chan<- playedGame) {
+	r := rand.New(rand.NewSource(s.Seed))
+	players := make([]Player, len(s.Factories))
+	defer func() {
+		for _, player := range players {
+			if player != nil {
+				ClosePlayer(player)
+			}
+		}
+	}()
+	for job := range jobs {
+		r.Seed(s.Seed + int64(job.Round))
+		game, err := s.play(ctx, job, players, r)
+		results <- playedGame{scheduledGame: job, Game: game, Err: err}
+	}
+}
+
+// play joga uma partida, criando os jogadores do worker que ainda não
+// tiverem sido criados
+func (s *gameScheduler) play(ctx context.Context, job scheduledGame, players []Player, r *rand.Rand) (*chess.Game, error) {
+	for _, i := range []int{job.White, job.Black} {
+		if players[i] != nil {
+			continue
+		}
+		player, err := s.Factories[i](r)
+		if err != nil {
+			return nil, err
+		}
+		players[i] = player
+	}
+	white, black := players[job.White], players[job.Black]
+
+	game, err := StartingGame(job.Opening)
+	if err != nil {
+		return nil, err
+	}
+	game.AddTagPair("Event", s.Event)
+	game.AddTagPair("Date", time.Now().Format("2006.01.02"))
+	game.AddTagPair("Round", strconv.Itoa(job.Round))
+	game.AddTagPair("White", white.Name())
+	game.AddTagPair("Black", black.Name())
+	game.AddTagPair("Result", chess.NoOutcome.String())
+	TagStartingPosition(game)
+
+	runner := &GameRunner{White: white, Black: black}
+	if s.TimeControl != nil {
+		runner.Clock = NewClock(*s.TimeControl)
+		game.AddTagPair("TimeControl", s.TimeControl.String())
+	}
+	// Os jogadores são reaproveitados entre as partidas do worker, então o
+	// relógio de cada partida, ou a falta dele, é sempre informado
+	for _, player := range []Player{white, black} {
+		if timed, ok := player.(TimedPlayer); ok {
+			timed.UseClock(runner.Clock)
+		}
+	}
+	if err := runner.NewGame(); err != nil {
+		return nil, err
+	}
+	if err := runner.Play(ctx, game); err != nil {
+		return nil, err
+	}
+	game.AddTagPair("Result", game.Outcome().String())
+	return game, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// randomFactory cria jogadores aleatórios que usam os números aleatórios do
+// worker, de forma que as partidas dependam apenas da semente
+func randomFactory(r *rand.Rand) (Player, error) {
+	return &RandomPlayer{Rand: r}, nil
+}
+
+// rookEndgame é uma abertura com poucas peças, em que as partidas entre
+// jogadores aleatórios terminam rápido
+var rookEndgame = func() *chess.Game {
+	option, err := chess.FEN("r3k3/8/8/8/8/8/8/R3K3 w - - 0 1")
+	if err != nil {
+		panic(err)
+	}
+	return chess.NewGame(option)
+}()
+
+// alternateColors é a escala de partidas em que os jogadores 0 e 1 trocam de
+// cor a cada rodada
+func alternateColors(round int) scheduledGame {
+	if round%2 == 0 {
+		return scheduledGame{Round: round, White: 1, Black: 0, Opening: rookEndgame}
+	}
+	return scheduledGame{Round: round, White: 0, Black: 1, Opening: rookEndgame}
+}
+
+// runScheduler joga as partidas e retorna o PGN de cada uma, indexado pela
+// rodada
+func runScheduler(t *testing.T, concurrency, total int) map[int]string {
+	t.Helper()
+	s := &gameScheduler{
+		Factories:   []PlayerFactory{randomFactory, randomFactory},
+		Concurrency: concurrency,
+		Seed:        7,
+		Event:       "test",
+	}
+	games := make(map[int]string)
+	err := s.Run(context.Background(), total, alternateColors, func(r playedGame) bool {
+		if _, ok := games[r.Round]; ok {
+			t.Errorf("round %d was reported twice", r.Round)
+		}
+		// A data muda de um dia para o outro, então fica fora da comparação
+		r.Game.RemoveTagPair("Date")
+		games[r.Round] = r.Game.String()
+		return false
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return games
+}
+
+func TestGameSchedulerConcurrency(t *testing.T) {
+	const total = 8
+	sequential := runScheduler(t, 1, total)
+	concurrent := runScheduler(t, 4, total)
+	for round := 1; round <= total; round++ {
+		if _, ok := concurrent[round]; !ok {
+			t.Errorf("round %d was not reported", round)
+			continue
+		}
+		// Cada partida usa a semente somada à rodada, então o resultado não
+		// depende de qual worker a jogou
+		if concurrent[round] != sequential[round] {
+			t.Errorf("round %d differs with 4 workers:\n%s\nwith 1 worker:\n%s", round, concurrent[round], sequential[round])
+		}
+	}
+	if len(concurrent) != total {
+		t.Errorf("%d games were reported, want %d", len(concurrent), total)
+	}
+}
+
+func TestGameSchedulerStop(t *testing.T) {
+	// Sem limite de rodadas, a série só termina quando onResult pede
+	s := &gameScheduler{
+		Factories:   []PlayerFactory{randomFactory, randomFactory},
+		Concurrency: 4,
+		Seed:        7,
+	}
+	reported := 0
+	err := s.Run(context.Background(), 0, alternateColors, func(r playedGame) bool {
+		reported++
+		return reported == 5
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if reported != 5 {
+		t.Errorf("%d games were reported after the stop, want 5", reported)
+	}
+}
+
+// closingPlayer é um jogador aleatório que conta quantas instâncias estão
+// abertas
+type closingPlayer struct {
+	RandomPlayer
+	open *int32
+}
+
+func (p *closingPlayer) Close() error {
+	atomic.AddInt32(p.open, -1)
+	return nil
+}
+
+func TestGameSchedulerFactoryError(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	// As duas primeiras instâncias são criadas, as demais falham
+	var open, created int32
+	errFactory := errors.New("factory failed")
+	factory := func(r *rand.Rand) (Player, error) {
+		if atomic.AddInt32(&created, 1) > 2 {
+			return nil, errFactory
+		}
+		atomic.AddInt32(&open, 1)
+		return &closingPlayer{RandomPlayer: RandomPlayer{Rand: r}, open: &open}, nil
+	}
+	s := &gameScheduler{
+		Factories:   []PlayerFactory{factory, randomFactory},
+		Concurrency: 4,
+		Seed:        7,
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(context.Background(), 0, alternateColors, func(r playedGame) bool {
+			return false
+		})
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, errFactory) {
+			t.Errorf("Run error = %v, want %v", err, errFactory)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not stop after a factory error")
+	}
+
+	// Todos os workers terminaram e fecharam os seus jogadores
+	if n := atomic.LoadInt32(&open); n != 0 {
+		t.Errorf("%d players were not closed", n)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines are still running after Run, there were %d before it", n, before)
+	}
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// infinityScore é um valor maior que qualquer avaliação possível de uma
+// posição, usado como limite inicial da janela Alfa-Beta
+const infinityScore = 1000000
+
+// defaultHashMB é o tamanho padrão da tabela de transposição, em megabytes
+const defaultHashMB = 16
+
+// maxSearchDepth limita o aprofundamento iterativo quando nenhum limite de
+// profundidade ou de tempo for informado
+const maxSearchDepth = 64
+
+// errSearchAborted indica que a busca foi interrompida antes de terminar a
+// iteração atual, normalmente porque o tempo disponível acabou
+var errSearchAborted = errors.New("search aborted")
+
+// SearchLimits define até onde a busca por uma jogada pode ir
+type SearchLimits struct {
+	// Depth é a profundidade máxima, em meias-jogadas, a ser alcançada.
+	// Zero significa sem limite de profundidade
+	Depth int
+	// MoveTime é o tempo máximo que a busca pode gastar para escolher uma
+	// jogada. Zero significa sem limite de tempo
+	MoveTime time.Duration
+}
+
+// SearchResult representa a melhor jogada encontrada pela última iteração
+// completa do aprofundamento iterativo
+type SearchResult struct {
+	// Move é a jogada escolhida para a posição da raiz
+	Move *chess.Move
+	// Score é a avaliação da jogada do ponto de vista de quem joga na raiz
+	Score int
+	// Depth é a profundidade da última iteração completa
+	Depth int
+	// PV é a variante principal, a sequência de jogadas esperada a partir
+	// da raiz, começando por Move
+	PV []*chess.Move
+	// Nodes é o número de posições visitadas pela busca, somando todas as
+	// iterações
+	Nodes int64
+	// Time é o tempo gasto pela busca até o fim da iteração
+	Time time.Duration
+}
+
+// Engine guarda o estado da busca que deve sobreviver entre as jogadas de
+// uma mesma partida, como a tabela de transposição e o histórico usado na
+// ordenação das jogadas
+type Engine struct {
+	// Evaluator é o avaliador usado nas folhas da busca
+	Evaluator Evaluator
+	// Contempt é o quanto a IA considera um empate pior que zero para si
+	// mesma, evitando que ela aceite empates em posições equilibradas
+	Contempt int
+	// OnIteration, quando informado, é chamado ao fim de cada iteração
+	// completa do aprofundamento iterativo
+	OnIteration func(*SearchResult)
+
+	tt       *TranspositionTable
+	ordering moveOrdering
+	nodes    int64
+	// history guarda as chaves das posições da partida e do caminho atual
+	// da busca, usado para detectar repetições
+	history   []uint64
+	rootColor chess.Color
+}
+
+// NewEngine cria um motor de busca com uma tabela de transposição do
+// tamanho informado, em megabytes
+func NewEngine(hashMB int) *Engine {
+	return &Engine{
+		Evaluator: MaterialEvaluator{},
+		tt:        NewTranspositionTable(hashMB),
+	}
+}
+
+// SetHashSize troca a tabela de transposição por uma nova do tamanho
+// informado, em megabytes
+func (e *Engine) SetHashSize(hashMB int) {
+	e.tt = NewTranspositionTable(hashMB)
+}
+
+// NewGame descarta o estado guardado de partidas anteriores
+func (e *Engine) NewGame() {
+	e.tt.Clear()
+	e.ordering.clear()
+}
+
+// IterativeDeepening busca a melhor jogada para o tabuleiro informado com
+// profundidades 1, 2, 3... até que os limites sejam atingidos ou o contexto
+// seja cancelado, retornando sempre a melhor jogada da última iteração
+// completa
+func (e *Engine) IterativeDeepening(ctx context.Context, game *chess.Game, limits SearchLimits) (*SearchResult, error) {
+	start := time.Now()
+	if limits.MoveTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.MoveTime)
+		defer cancel()
+	}
+	maxDepth := limits.Depth
+	if maxDepth <= 0 || maxDepth > maxSearchDepth {
+		maxDepth = maxSearchDepth
+	}
+
+	pos := game.Position()
+	ordering := pos.ValidMoves()
+	if len(ordering) == 0 {
+		return nil, errors.New("there are no valid moves left")
+	}
+	e.nodes = 0
+	e.ordering.newSearch()
+	e.rootColor = pos.Turn()
+	e.history = e.history[:0]
+	for _, p := range game.Positions() {
+		e.history = append(e.history, positionKey(p))
+	}
+	entry, _ := e.tt.Probe(positionKey(pos))
+	e.ordering.sortMoves(pos, ordering, entry.move, 0)
+	var result *SearchResult
+	for depth := 1; depth <= maxDepth; depth++ {
+		// A primeira iteração sempre é concluída para que exista ao menos
+		// uma jogada a ser retornada
+		iterCtx := ctx
+		if depth == 1 {
+			iterCtx = context.Background()
+		}
+
+		score, pv, err := e.searchRoot(iterCtx, pos, depth, ordering)
+		if err != nil {
+			break
+		}
+		result = &SearchResult{
+			Move:  pv[0],
+			Score: score,
+			Depth: depth,
+			PV:    pv,
+			Nodes: e.nodes,
+			Time:  time.Since(start),
+		}
+		if e.OnIteration != nil {
+			e.OnIteration(result)
+		}
+		// A melhor jogada desta iteração é a primeira a ser buscada na
+		// próxima, o que costuma gerar mais cortes no Alfa-Beta
+		moveToFront(ordering, pv[0])
+
+		// Não há motivo para continuar procurando se existe uma única
+		// jogada possível na raiz ou se um mate já foi encontrado dentro da
+		// profundidade buscada
+		if len(ordering) == 1 || (score >= maxMateScore && mateScore-score <= depth) {
+			break
+		}
+	}
+	return result, nil
+}
+
+// AllocateMoveTime decide quanto tempo uma jogada pode gastar, dado o tempo
+// restante no relógio, o incremento recebido a cada jogada e o número de
+// jogadas até o próximo controle de tempo (zero quando não há)
+func AllocateMoveTime(remaining, increment time.Duration, movesToGo int) time.Duration {
+	if movesToGo <= 0 {
+		movesToGo = 30
+	}
+	moveTime := remaining/time.Duration(movesToGo) + increment*3/4
+	// Sempre deixa uma margem no relógio para a comunicação com a interface
+	if limit := remaining - remaining/10 - 50*time.Millisecond; moveTime > limit {
+		moveTime = limit
+	}
+	if moveTime < 10*time.Millisecond {
+		moveTime = 10 * time.Millisecond
+	}
+	return moveTime
+}
+
+// searchRoot avalia as jogadas da raiz na ordem informada, estreitando a
+// janela Alfa-Beta a cada jogada melhor encontrada, e retorna a avaliação
+// e a variante principal da melhor delas
+func (e *Engine) searchRoot(ctx context.Context, pos *chess.Position, depth int, ordering []*chess.Move) (int, []*chess.Move, error) {
+	e.nodes++
+	alpha, beta := -infinityScore, infinityScore
+	var pv []*chess.Move
+	for _, move := range ordering {
+		score, line, err := e.negamax(ctx, pos.Update(move), depth-1, 1, -beta, -alpha)
+		if err != nil {
+			return 0, nil, err
+		}
+		score = -score
+		if pv == nil || score > alpha {
+			alpha = score
+			pv = append([]*chess.Move{move}, line...)
+		}
+	}
+	e.tt.Store(positionKey(pos), depth, scoreToTT(alpha, 0), BoundExact, pv[0])
+	return alpha, pv, nil
+}
+
+// negamax aplica o algoritmo Alfa-Beta a partir de uma posição, sempre do
+// ponto de vista de quem joga nela, gerando as jogadas de cada nó somente
+// quando ele é visitado. Retorna a avaliação da posição e a variante
+// principal encontrada a partir dela
+func (e *Engine) negamax(ctx context.Context, pos *chess.Position, depth, ply, alpha, beta int) (int, []*chess.Move, error) {
+	if ctx.Err() != nil {
+		return 0, nil, errSearchAborted
+	}
+	e.nodes++
+
+	// A repetição de uma posição já vista na partida ou no caminho atual
+	// é tratada como empate, já que quem joga poderia repeti-la de novo
+	key := positionKey(pos)
+	if e.isRepetition(key) {
+		return e.drawScore(pos), nil, nil
+	}
+
+	// Consulta a tabela de transposição, que pode encerrar a busca neste nó
+	// caso a posição já tenha sido buscada com profundidade suficiente
+	entry, found := e.tt.Probe(key)
+	if found && int(entry.depth) >= depth {
+		score := scoreFromTT(int(entry.score), ply)
+		switch {
+		case entry.bound == BoundExact:
+			if entry.move != nil {
+				return score, []*chess.Move{entry.move}, nil
+			}
+			return score, nil, nil
+		case entry.bound == BoundLower && score >= beta:
+			return beta, nil, nil
+		case entry.bound == BoundUpper && score <= alpha:
+			return alpha, nil, nil
+		}
+	}
+
+	if depth == 0 {
+		score, err := e.quiescence(ctx, pos, ply, alpha, beta)
+		return score, nil, err
+	}
+	moves := pos.ValidMoves()
+	if score, ok := evaluateTerminal(pos, moves, ply, e.drawScore(pos)); ok {
+		return score, nil, nil
+	}
+	e.ordering.sortMoves(pos, moves, entry.move, ply)
+
+	e.history = append(e.history, key)
+	defer func() { e.history = e.history[:len(e.history)-1] }()
+
+	alphaOrig := alpha
+	var pv []*chess.Move
+	for _, move := range moves {
+		score, line, err := e.negamax(ctx, pos.Update(move), depth-1, ply+1, -beta, -alpha)
+		if err != nil {
+			return 0, nil, err
+		}
+		score = -score
+		if score >= beta {
+			e.ordering.storeCutoff(pos, move, depth, ply)
+			e.tt.Store(key, depth, scoreToTT(beta, ply), BoundLower, move)
+			return beta, nil, nil
+		}
+		if score > alpha {
+			alpha = score
+			pv = append([]*chess.Move{move}, line...)
+		}
+	}
+	if alpha > alphaOrig {
+		e.tt.Store(key, depth, scoreToTT(alpha, ply), BoundExact, pv[0])
+	} else {
+		e.tt.Store(key, depth, scoreToTT(alpha, ply), BoundUpper, nil)
+	}
+	return alpha, pv, nil
+}
+
+// quiescence continua a busca além do horizonte explorando apenas capturas
+// e promoções até que a posição fique calma, evitando que a IA avalie uma
+// posição no meio de uma troca de peças. Quem joga pode sempre escolher não
+// capturar, então a avaliação estática serve como limite inferior (stand pat)
+func (e *Engine) quiescence(ctx context.Context, pos *chess.Position, ply, alpha, beta int) (int, error) {
+	if ctx.Err() != nil {
+		return 0, errSearchAborted
+	}
+	e.nodes++
+
+	moves := pos.ValidMoves()
+	if score, ok := evaluateTerminal(pos, moves, ply, e.drawScore(pos)); ok {
+		return score, nil
+	}
+
+	standPat := e.evaluateRelative(pos)
+	if standPat >= beta {
+		return beta, nil
+	}
+	if standPat > alpha {
+		alpha = standPat
+	}
+
+	var captures []*chess.Move
+	for _, move := range moves {
+		if isNoisyMove(move) {
+			captures = append(captures, move)
+		}
+	}
+	sortCaptures(pos, captures)
+	for _, move := range captures {
+		score, err := e.quiescence(ctx, pos.Update(move), ply+1, -beta, -alpha)
+		if err != nil {
+			return 0, err
+		}
+		score = -score
+		if score >= beta {
+			return beta, nil
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	return alpha, nil
+}
+
+// isNoisyMove verifica se a jogada altera o material do tabuleiro, ou seja,
+// se é uma captura ou uma promoção
+func isNoisyMove(move *chess.Move) bool {
+	return move.HasTag(chess.Capture) || move.HasTag(chess.EnPassant) || move.Promo() != chess.NoPieceType
+}
+
+// isRepetition verifica se a posição já ocorreu antes na partida ou no
+// caminho atual da busca
+func (e *Engine) isRepetition(key uint64) bool {
+	for _, k := range e.history {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// drawScore retorna a avaliação de um empate do ponto de vista de quem joga
+// na posição, aplicando o contempt contra o lado que não é o da IA
+func (e *Engine) drawScore(pos *chess.Position) int {
+	if pos.Turn() == e.rootColor {
+		return -e.Contempt
+	}
+	return e.Contempt
+}
+
+// evaluateRelative avalia a posição do ponto de vista de quem joga nela
+func (e *Engine) evaluateRelative(pos *chess.Position) int {
+	if pos.Turn() == chess.Black {
+		return -e.Evaluator.Evaluate(pos)
+	}
+	return e.Evaluator.Evaluate(pos)
+}
+
+// moveToFront move a jogada informada para o início da lista, mantendo a
+// ordem relativa das demais
+func moveToFront(moves []*chess.Move, move *chess.Move) {
+	for i, m := range moves {
+		if m == move {
+			copy(moves[1:i+1], moves[:i])
+			moves[0] = move
+			return
+		}
+	}
+}
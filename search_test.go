@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// search busca a melhor jogada até a profundidade informada
+func search(t *testing.T, e *Engine, game *chess.Game, depth int) *SearchResult {
+	t.Helper()
+	result, err := e.IterativeDeepening(context.Background(), game, SearchLimits{Depth: depth})
+	if err != nil {
+		t.Fatalf("IterativeDeepening failed: %v", err)
+	}
+	return result
+}
+
+func TestSearchFindsMate(t *testing.T) {
+	tests := []struct {
+		fen   string
+		move  string
+		score int
+	}{
+		// Mate no corredor com a torre
+		{"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", "a1a8", mateScore - 1},
+		// O mesmo mate para as pretas
+		{"r5k1/5ppp/8/8/8/8/5PPP/6K1 b - - 0 1", "a8a1", mateScore - 1},
+		// Mate com a dama protegida pelo rei
+		{"7k/8/5KQ1/8/8/8/8/8 w - - 0 1", "g6g7", mateScore - 1},
+	}
+	for _, tt := range tests {
+		for _, depth := range []int{2, 4} {
+			result := search(t, NewEngine(1), gameFromFEN(t, tt.fen), depth)
+			if result.Move.String() != tt.move || result.Score != tt.score {
+				t.Errorf("%s at depth %d: got %s with score %d, want %s with score %d",
+					tt.fen, depth, result.Move, result.Score, tt.move, tt.score)
+			}
+		}
+	}
+}
+
+func TestSearchRepetition(t *testing.T) {
+	// Os cavalos voltaram para as casas iniciais e saíram de novo, então Ng1
+	// repete uma posição que já ocorreu na partida
+	game := gameAfter(t, "Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6")
+	e := NewEngine(1)
+	search(t, e, game, 1)
+
+	repeated := gameAfter(t, "Nf3", "Nf6", "Ng1").Position()
+	if !e.isRepetition(positionKey(repeated)) {
+		t.Error("a position from the game history is not detected as a repetition")
+	}
+	fresh := gameAfter(t, "Nf3", "Nf6", "Nc3").Position()
+	if e.isRepetition(positionKey(fresh)) {
+		t.Error("a position that never occurred is detected as a repetition")
+	}
+}
+
+func TestSearchContempt(t *testing.T) {
+	// Todas as jogadas deixam o material igual, então a única diferença
+	// entre elas é se Ng1 repete a posição e empata a partida
+	tests := []struct {
+		contempt int
+		score    int
+		wantDraw bool
+	}{
+		// Com contempt positivo a IA considera o empate pior que zero e o
+		// evita
+		{20, 0, false},
+		// Com contempt negativo ela considera o empate melhor que zero e o
+		// procura
+		{-20, 20, true},
+	}
+	for _, tt := range tests {
+		game := gameAfter(t, "Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6")
+		e := NewEngine(1)
+		e.Contempt = tt.contempt
+		result := search(t, e, game, 1)
+		draw := result.Move.String() == "f3g1"
+		if draw != tt.wantDraw || result.Score != tt.score {
+			t.Errorf("contempt %d: got %s with score %d, want the draw: %t with score %d",
+				tt.contempt, result.Move, result.Score, tt.wantDraw, tt.score)
+		}
+
+		// O empate vale -contempt para o lado da IA e +contempt para o
+		// adversário
+		if got := e.drawScore(game.Position()); got != -tt.contempt {
+			t.Errorf("contempt %d: drawScore for the AI side = %d, want %d", tt.contempt, got, -tt.contempt)
+		}
+		opponent := gameAfter(t, "Nf3").Position()
+		if got := e.drawScore(opponent); got != tt.contempt {
+			t.Errorf("contempt %d: drawScore for the opponent = %d, want %d", tt.contempt, got, tt.contempt)
+		}
+	}
+}
+
+// minimax é uma busca de referência sem Alfa-Beta, tabela de transposição ou
+// ordenação, que visita todas as jogadas até a profundidade informada. As
+// folhas são avaliadas pela quiescence com a janela completa
+func minimax(t *testing.T, e *Engine, pos *chess.Position, depth, ply int) int {
+	if depth == 0 {
+		score, err := e.quiescence(context.Background(), pos, ply, -infinityScore, infinityScore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return score
+	}
+	moves := pos.ValidMoves()
+	if score, ok := evaluateTerminal(pos, moves, ply, e.drawScore(pos)); ok {
+		return score
+	}
+	best := -infinityScore
+	for _, move := range moves {
+		if score := -minimax(t, e, pos.Update(move), depth-1, ply+1); score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// searchPositions são posições com poucas peças, algumas delas táticas,
+// usadas para comparar a busca com a busca de referência
+var searchPositions = []string{
+	"4k3/8/4p3/3p4/8/8/8/3QK3 w - - 0 1",
+	"8/8/3k4/8/2KP4/8/8/8 w - - 0 1",
+	"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1",
+	"r3k3/8/8/3n4/8/2N5/8/4K2R w Kq - 0 1",
+	"4k3/8/3r4/1b6/3N4/2B5/5P2/4K3 b - - 0 1",
+}
+
+func TestSearchMatchesMinimax(t *testing.T) {
+	for _, evaluator := range []Evaluator{MaterialEvaluator{}, NewPositionalEvaluator()} {
+		for _, fen := range searchPositions {
+			for depth := 1; depth <= 3; depth++ {
+				e := NewEngine(1)
+				e.Evaluator = evaluator
+				game := gameFromFEN(t, fen)
+				result := search(t, e, game, depth)
+				if want := minimax(t, e, game.Position(), depth, 0); result.Score != want {
+					t.Errorf("%T, %s at depth %d: score %d, want %d from minimax",
+						evaluator, fen, depth, result.Score, want)
+				}
+			}
+		}
+	}
+}
+
+func TestSearchPVIsLegal(t *testing.T) {
+	for _, fen := range searchPositions {
+		game := gameFromFEN(t, fen)
+		result := search(t, NewEngine(1), game, 4)
+		if len(result.PV) == 0 || result.PV[0] != result.Move {
+			t.Errorf("%s: the PV %v does not start with the best move %s", fen, result.PV, result.Move)
+			continue
+		}
+		for i, move := range result.PV {
+			if err := MoveUCI(game, move.String()); err != nil {
+				t.Errorf("%s: move %d of the PV %v is not legal: %v", fen, i+1, result.PV, err)
+				break
+			}
+		}
+	}
+}
+
+func TestSearchHorizon(t *testing.T) {
+	// Qxd5 ganha um peão no horizonte da busca, mas exd5 captura a dama em
+	// seguida
+	fen := "4k3/8/4p3/3p4/8/8/8/3QK3 w - - 0 1"
+	for _, evaluator := range []Evaluator{MaterialEvaluator{}, NewPositionalEvaluator()} {
+		for depth := 1; depth <= 3; depth++ {
+			e := NewEngine(1)
+			e.Evaluator = evaluator
+			result := search(t, e, gameFromFEN(t, fen), depth)
+			if result.Move.String() == "d1d5" {
+				t.Errorf("%T at depth %d: the queen takes the defended pawn on d5", evaluator, depth)
+			}
+		}
+	}
+}
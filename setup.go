@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/notnil/chess"
+)
+
+// LoadStartingGame cria a partida de onde o jogo começa: a posição FEN
+// informada, a partida do arquivo PGN informado ou, sem nenhum dos dois, a
+// posição inicial
+func LoadStartingGame(fen, pgnPath string) (*chess.Game, error) {
+	switch {
+	case fen != "" && pgnPath != "":
+		return nil, fmt.Errorf("use either a FEN or a PGN to start the game, not both")
+	case fen != "":
+		option, err := chess.FEN(fen)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FEN %q: %w", fen, err)
+		}
+		game := chess.NewGame(option)
+		if err := ValidatePosition(game.Position()); err != nil {
+			return nil, fmt.Errorf("invalid FEN %q: %w", fen, err)
+		}
+		TagStartingPosition(game)
+		return checkNotOver(game)
+	case pgnPath != "":
+		f, err := os.Open(pgnPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		option, err := chess.PGN(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PGN in %s: %w", pgnPath, err)
+		}
+		game := chess.NewGame(option)
+		if err := ValidatePosition(game.Positions()[0]); err != nil {
+			return nil, fmt.Errorf("invalid PGN in %s: %w", pgnPath, err)
+		}
+		return checkNotOver(game)
+	}
+	return chess.NewGame(), nil
+}
+
+// checkNotOver retorna um erro se a partida já tiver terminado, já que não
+// há como continuá-la. Um PGN sem o resultado ao fim das jogadas é lido com
+// o resultado vazio, então a partida é reconstruída como em andamento
+func checkNotOver(game *chess.Game) (*chess.Game, error) {
+	if game.Outcome() == "" {
+		rebuilt, err := rebuildGame(game, len(game.Moves()), chess.NoOutcome, chess.NoMethod)
+		if err != nil {
+			return nil, err
+		}
+		game = rebuilt
+	}
+	if game.Outcome() != chess.NoOutcome {
+		return nil, fmt.Errorf("the game is already over: %s {%s}", game.Outcome(), describeOutcome(game))
+	}
+	if len(game.ValidMoves()) == 0 {
+		return nil, fmt.Errorf("the side to move has no legal moves")
+	}
+	return game, nil
+}
+
+// ValidatePosition verifica se a posição poderia acontecer em uma partida:
+// cada lado tem exatamente um rei, não há peões na primeira nem na última
+// fileira e o lado que acabou de jogar não deixou o seu rei em xeque
+func ValidatePosition(pos *chess.Position) error {
+	sm := pos.Board().SquareMap()
+	kings := [2]int{}
+	for sq, piece := range sm {
+		switch piece.Type() {
+		case chess.King:
+			kings[colorIndex(piece.Color())]++
+		case chess.Pawn:
+			if rank := sq.Rank(); rank == chess.Rank1 || rank == chess.Rank8 {
+				return fmt.Errorf("there is a pawn on %s", sq)
+			}
+		}
+	}
+	if kings[colorIndex(chess.White)] != 1 || kings[colorIndex(chess.Black)] != 1 {
+		return fmt.Errorf("each side must have exactly one king")
+	}
+
+	bs := newBoardState(sm)
+	waiting := pos.Turn().Other()
+	king := uint64(1) << uint(bs.kings[colorIndex(waiting)])
+	for sq, piece := range sm {
+		if piece.Color() == pos.Turn() && bs.attacksFrom(sq)&king != 0 {
+			return fmt.Errorf("the side not to move is in check")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestLoadStartingGamePGN(t *testing.T) {
+	tests := []struct {
+		name    string
+		pgn     string
+		wantErr bool
+	}{
+		{"in progress", "[Event \"test\"]\n\n1. e4 e5 2. Nf3 *\n", false},
+		{"without result", "[Event \"test\"]\n\n1. e4 e5 2. Nf3\n", false},
+		{"finished", "[Event \"test\"]\n\n1. e4 e5 2. Nf3 1-0\n", true},
+		{"checkmate", "1. f3 e5 2. g4 Qh4#\n", true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "game.pgn")
+		if err := os.WriteFile(path, []byte(tt.pgn), 0644); err != nil {
+			t.Fatal(err)
+		}
+		game, err := LoadStartingGame("", path)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: LoadStartingGame should have failed", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: LoadStartingGame failed: %v", tt.name, err)
+			continue
+		}
+		if game.Outcome() != chess.NoOutcome || len(game.Moves()) != 3 {
+			t.Errorf("%s: got outcome %q after %d moves, want %q after 3 moves", tt.name, game.Outcome(), len(game.Moves()), chess.NoOutcome)
+		}
+	}
+}
+
+func TestLoadStartingGameFEN(t *testing.T) {
+	for _, fen := range []string{
+		"8/8/8/8/8/8/8/8 w - - 0 1",
+		"4k3/8/8/8/8/8/8/P3K3 w - - 0 1",
+		"4k3/8/8/8/8/8/4r3/4K3 b - - 0 1",
+	} {
+		if _, err := LoadStartingGame(fen, ""); err == nil {
+			t.Errorf("LoadStartingGame(%q) should have failed", fen)
+		}
+	}
+	game, err := LoadStartingGame("4k3/8/8/8/8/8/8/4K2R w K - 0 1", "")
+	if err != nil {
+		t.Fatalf("LoadStartingGame failed: %v", err)
+	}
+	if tag := game.GetTagPair("FEN"); tag == nil {
+		t.Error("the FEN tag is missing from a game started from a position")
+	}
+}
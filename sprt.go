@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/notnil/chess"
+	"github.com/spf13/viper"
+)
+
+// SPRTCommand é o nome do comando que compara dois jogadores com um teste
+// sequencial da razão de probabilidades (SPRT)
+const SPRTCommand = "sprt"
+
+// SPRTDecision é a conclusão de um teste SPRT
+type SPRTDecision int
+
+const (
+	// SPRTContinue indica que ainda não há partidas suficientes para decidir
+	SPRTContinue SPRTDecision = iota
+	// SPRTAcceptH0 indica que o candidato não é melhor que elo0
+	SPRTAcceptH0
+	// SPRTAcceptH1 indica que o candidato é pelo menos elo1 melhor
+	SPRTAcceptH1
+)
+
+// String descreve a decisão do teste
+func (d SPRTDecision) String() string {
+	switch d {
+	case SPRTAcceptH0:
+		return "H0 accepted"
+	case SPRTAcceptH1:
+		return "H1 accepted"
+	}
+	return "no decision"
+}
+
+// SPRT é um teste sequencial que decide, com o menor número de partidas
+// possível, entre as hipóteses de o candidato ser elo0 (H0) ou elo1 (H1)
+// pontos de Elo melhor que a referência
+type SPRT struct {
+	Elo0 float64
+	Elo1 float64
+	// Alpha é a probabilidade de aceitar H1 quando H0 é verdadeira
+	Alpha float64
+	// Beta é a probabilidade de aceitar H0 quando H1 é verdadeira
+	Beta float64
+}
+
+// Bounds retorna os limites da razão de verossimilhança logarítmica (LLR)
+// abaixo do qual H0 é aceita e acima do qual H1 é aceita
+func (s SPRT) Bounds() (float64, float64) {
+	return math.Log(s.Beta / (1 - s.Alpha)), math.Log((1 - s.Beta) / s.Alpha)
+}
+
+// LLR calcula a razão de verossimilhança logarítmica do placar do candidato,
+// usando a aproximação normal do modelo trinomial de vitórias, empates e
+// derrotas
+func (s SPRT) LLR(score MatchScore) float64 {
+	if score.Games() == 0 {
+		return 0
+	}
+	// Enquanto todas as partidas tiverem o mesmo resultado a variância seria
+	// zero, então um empate fictício é considerado
+	wins, draws, losses := float64(score.Wins), float64(score.Draws), float64(score.Losses)
+	if wins == 0 && draws == 0 || wins == 0 && losses == 0 || draws == 0 && losses == 0 {
+		draws++
+	}
+	n := wins + draws + losses
+	x := (wins + draws/2) / n
+	variance := (wins*math.Pow(1-x, 2) + draws*math.Pow(0.5-x, 2) + losses*math.Pow(x, 2)) / n
+	// Só empates ainda não dizem nada sobre a diferença entre os jogadores
+	if variance == 0 {
+		return 0
+	}
+	s0, s1 := scoreFromElo(s.Elo0), scoreFromElo(s.Elo1)
+	return n * (s1 - s0) * (2*x - s0 - s1) / (2 * variance)
+}
+
+// Decide compara a LLR do placar com os limites do teste
+func (s SPRT) Decide(score MatchScore) SPRTDecision {
+	llr := s.LLR(score)
+	lower, upper := s.Bounds()
+	if llr <= lower {
+		return SPRTAcceptH0
+	} else if llr >= upper {
+		return SPRTAcceptH1
+	}
+	return SPRTContinue
+}
+
+// scoreFromElo converte uma diferença de Elo na fração de pontos esperada
+// segundo o modelo logístico
+func scoreFromElo(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// RunSPRTCommand executa o comando sprt com os argumentos de linha de
+// comando. O --player1 é o candidato e o --player2 é a referência, e as
+// partidas continuam até que o teste aceite uma das hipóteses ou o número
+// máximo de partidas seja atingido
+func RunSPRTCommand(out io.Writer) error {
+	test := SPRT{
+		Elo0:  viper.GetFloat64(ELO0),
+		Elo1:  viper.GetFloat64(ELO1),
+		Alpha: viper.GetFloat64(ALPHA),
+		Beta:  viper.GetFloat64(BETA),
+	}
+	if test.Elo1 <= test.Elo0 {
+		return fmt.Errorf("elo1 must be greater than elo0")
+	}
+	if test.Alpha <= 0 || test.Alpha >= 1 || test.Beta <= 0 || test.Beta >= 1 {
+		return fmt.Errorf("alpha and beta must be between 0 and 1")
+	}
+
+	candidate, baseline := viper.GetString(PLAYER1), viper.GetString(PLAYER2)
+	timeControl, err := TimeControlFlag()
+	if err != nil {
+		return err
+	}
+	var openings []*chess.Game
+	if path := viper.GetString(OPENINGS); path != "" {
+		if openings, err = LoadOpenings(path); err != nil {
+			return err
+		}
+	}
+	var pgnOut io.Writer
+	if path := viper.GetString(PGN_OUT); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		pgnOut = f
+	}
+
+	lower, upper := test.Bounds()
+	match := &Match{
+		Player1:     QuietPlayerFactory(candidate),
+		Player2:     QuietPlayerFactory(baseline),
+		Games:       viper.GetInt(MAX_GAMES),
+		Openings:    openings,
+		Event:       "puc-chess sprt",
+		Concurrency: viper.GetInt(CONCURRENCY),
+		Seed:        viper.GetInt64(SEED),
+		TimeControl: timeControl,
+		OnGameEnd: func(round int, game *chess.Game, score MatchScore) {
+			fmt.Fprintf(out, "Game %d: %s {%s}, score %s, LLR %.2f [%.2f, %.2f]\n",
+				round, game.Outcome(), describeOutcome(game), score, test.LLR(score), lower, upper)
+			if pgnOut != nil {
+				fmt.Fprintf(pgnOut, "%s\n\n", game)
+			}
+		},
+		ShouldStop: func(score MatchScore) bool {
+			return test.Decide(score) != SPRTContinue
+		},
+	}
+	fmt.Fprintf(out, "SPRT: %s vs %s, elo0=%g elo1=%g alpha=%g beta=%g%s\n",
+		candidate, baseline, test.Elo0, test.Elo1, test.Alpha, test.Beta, describeTimeControl(timeControl))
+	score, _, err := match.Run(context.Background())
+	printMatchScore(out, candidate, baseline, score)
+	fmt.Fprintf(out, "LLR: %.2f [%.2f, %.2f], %s\n", test.LLR(score), lower, upper, test.Decide(score))
+	return err
+}
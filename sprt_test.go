@@ -0,0 +1,40 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSPRTBounds(t *testing.T) {
+	lower, upper := SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}.Bounds()
+	if math.Abs(lower+2.944) > 0.001 || math.Abs(upper-2.944) > 0.001 {
+		t.Errorf("Bounds() = [%.3f, %.3f], want [-2.944, 2.944]", lower, upper)
+	}
+	lower, upper = SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.1}.Bounds()
+	if math.Abs(lower+2.251) > 0.001 || math.Abs(upper-2.890) > 0.001 {
+		t.Errorf("Bounds() = [%.3f, %.3f], want [-2.251, 2.890]", lower, upper)
+	}
+}
+
+func TestSPRTLLR(t *testing.T) {
+	tests := []struct {
+		test     SPRT
+		score    MatchScore
+		llr      float64
+		decision SPRTDecision
+	}{
+		{SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}, MatchScore{}, 0, SPRTContinue},
+		{SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}, MatchScore{Draws: 10}, 0, SPRTContinue},
+		{SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}, MatchScore{Wins: 1200, Draws: 1500, Losses: 1000}, 4.217, SPRTAcceptH1},
+		{SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}, MatchScore{Wins: 1000, Draws: 1500, Losses: 1200}, -5.512, SPRTAcceptH0},
+		{SPRT{Elo0: 0, Elo1: 10, Alpha: 0.05, Beta: 0.05}, MatchScore{Wins: 30, Draws: 40, Losses: 30}, -0.069, SPRTContinue},
+	}
+	for _, tt := range tests {
+		if llr := tt.test.LLR(tt.score); math.Abs(llr-tt.llr) > 0.001 {
+			t.Errorf("LLR(%s) with elo0=%g elo1=%g = %.3f, want %.3f", tt.score, tt.test.Elo0, tt.test.Elo1, llr, tt.llr)
+		}
+		if decision := tt.test.Decide(tt.score); decision != tt.decision {
+			t.Errorf("Decide(%s) = %s, want %s", tt.score, decision, tt.decision)
+		}
+	}
+}
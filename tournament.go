@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/spf13/viper"
+)
+
+// TournamentCommand é o nome do comando que executa um torneio entre várias
+// configurações de jogadores
+const TournamentCommand = "tournament"
+
+// Formatos de torneio aceitos pela opção --format
+const (
+	// RoundRobinFormat faz todos os jogadores se enfrentarem
+	RoundRobinFormat = "roundrobin"
+	// GauntletFormat faz o primeiro jogador enfrentar cada um dos demais
+	GauntletFormat = "gauntlet"
+)
+
+// Tournament é um torneio entre vários jogadores, em que cada confronto é
+// uma série de partidas com as cores alternadas
+type Tournament struct {
+	Names   []string
+	Players []PlayerFactory
+	// Format é o formato do torneio, RoundRobinFormat ou GauntletFormat
+	Format string
+	// GamesPerPairing é o número de partidas de cada confronto
+	GamesPerPairing int
+	// Openings são as posições de onde as partidas começam, usadas da mesma
+	// forma que em um Match
+	Openings    []*chess.Game
+	Event       string
+	Concurrency int
+	Seed        int64
+	// TimeControl, quando informado, é o controle de tempo das partidas
+	TimeControl *TimeControl
+	// OnGameEnd, quando informado, é chamado ao fim de cada partida com os
+	// índices dos jogadores de brancas e de pretas
+	OnGameEnd func(game *chess.Game, white, black int)
+}
+
+// TournamentResult guarda o resultado de um torneio
+type TournamentResult struct {
+	Names []string
+	// Scores guarda o placar de cada jogador, nas linhas, contra cada
+	// adversário, nas colunas
+	Scores [][]MatchScore
+	// Games são as partidas jogadas, em ordem de rodada
+	Games []*chess.Game
+}
+
+// pairings retorna os confrontos do torneio, de acordo com o seu formato
+func (t *Tournament) pairings() ([][2]int, error) {
+	var pairs [][2]int
+	switch t.Format {
+	case RoundRobinFormat:
+		for i := range t.Players {
+			for j := i + 1; j < len(t.Players); j++ {
+				pairs = append(pairs, [2]int{i, j})
+			}
+		}
+	case GauntletFormat:
+		for j := 1; j < len(t.Players); j++ {
+			pairs = append(pairs, [2]int{0, j})
+		}
+	default:
+		return nil, fmt.Errorf("unknown tournament format %q, it should be %s or %s", t.Format, RoundRobinFormat, GauntletFormat)
+	}
+	return pairs, nil
+}
+
+// Run joga todas as partidas do torneio
+func (t *Tournament) Run(ctx context.Context) (*TournamentResult, error) {
+	if len(t.Players) < 2 {
+		return nil, fmt.Errorf("a tournament needs at least two players")
+	}
+	if t.GamesPerPairing <= 0 {
+		return nil, fmt.Errorf("the number of games of each pairing should be positive")
+	}
+	pairs, err := t.pairings()
+	if err != nil {
+		return nil, err
+	}
+
+	// As partidas de cada confronto alternam as cores, e cada par de
+	// partidas usa a mesma abertura
+	var schedule []scheduledGame
+	for _, pair := range pairs {
+		for k := 0; k < t.GamesPerPairing; k++ {
+			game := scheduledGame{Round: len(schedule) + 1, White: pair[0], Black: pair[1]}
+			if k%2 == 1 {
+				game.White, game.Black = pair[1], pair[0]
+			}
+			if len(t.Openings) > 0 {
+				game.Opening = t.Openings[(k/2)%len(t.Openings)]
+			}
+			schedule = append(schedule, game)
+		}
+	}
+
+	seed := t.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	scheduler := &gameScheduler{
+		Factories:   t.Players,
+		Concurrency: t.Concurrency,
+		Seed:        seed,
+		Event:       t.Event,
+		TimeControl: t.TimeControl,
+	}
+
+	result := &TournamentResult{Names: t.Names, Scores: make([][]MatchScore, len(t.Players))}
+	for i := range result.Scores {
+		result.Scores[i] = make([]MatchScore, len(t.Players))
+	}
+	var played []playedGame
+	err = scheduler.Run(ctx, len(schedule), func(round int) scheduledGame {
+		return schedule[round-1]
+	}, func(r playedGame) bool {
+		points := WhitePoints(r.Game.Outcome())
+		result.Scores[r.White][r.Black].Add(points)
+		result.Scores[r.Black][r.White].Add(1 - points)
+		played = append(played, r)
+		if t.OnGameEnd != nil {
+			t.OnGameEnd(r.Game, r.White, r.Black)
+		}
+		return false
+	})
+
+	sort.Slice(played, func(i, j int) bool {
+		return played[i].Round < played[j].Round
+	})
+	for _, r := range played {
+		result.Games = append(result.Games, r.Game)
+	}
+	return result, err
+}
+
+// Total retorna o placar de um jogador contra todos os adversários
+func (r *TournamentResult) Total(player int) MatchScore {
+	var total MatchScore
+	for _, score := range r.Scores[player] {
+		total.Wins += score.Wins
+		total.Draws += score.Draws
+		total.Losses += score.Losses
+	}
+	return total
+}
+
+// Ranking retorna os índices dos jogadores ordenados pela pontuação, do
+// primeiro para o último colocado
+func (r *TournamentResult) Ranking() []int {
+	ranking := make([]int, len(r.Names))
+	for i := range ranking {
+		ranking[i] = i
+	}
+	sort.SliceStable(ranking, func(i, j int) bool {
+		return r.Total(ranking[i]).Points() > r.Total(ranking[j]).Points()
+	})
+	return ranking
+}
+
+// PrintCrosstable exibe a classificação do torneio, com a pontuação e o Elo
+// estimado de cada jogador em relação aos adversários que enfrentou, e o
+// placar de cada confronto
+func (r *TournamentResult) PrintCrosstable(out io.Writer) {
+	ranking := r.Ranking()
+	nameWidth := len("Player")
+	for _, name := range r.Names {
+		if len(name) > nameWidth {
+			nameWidth = len(name)
+		}
+	}
+
+	// As colunas dos confrontos são identificadas pela colocação do
+	// adversário
+	header := fmt.Sprintf("%4s  %-*s  %9s  %-16s", "Rank", nameWidth, "Player", "Score", "Elo")
+	for rank := range ranking {
+		header += fmt.Sprintf("  %7d", rank+1)
+	}
+	fmt.Fprintln(out, header)
+	fmt.Fprintln(out, strings.Repeat("-", len(header)))
+	for rank, player := range ranking {
+		total := r.Total(player)
+		elo, margin := total.EloDifference()
+		line := fmt.Sprintf("%4d  %-*s  %9s  %-16s", rank+1, nameWidth, r.Names[player],
+			fmt.Sprintf("%.1f/%d", total.Points(), total.Games()), FormatElo(elo, margin))
+		for _, opponent := range ranking {
+			score := r.Scores[player][opponent]
+			cell := ""
+			if opponent == player {
+				cell = "-"
+			} else if score.Games() > 0 {
+				cell = fmt.Sprintf("%.1f/%d", score.Points(), score.Games())
+			}
+			line += fmt.Sprintf("  %7s", cell)
+		}
+		fmt.Fprintln(out, line)
+	}
+}
+
+// RunTournamentCommand executa o comando tournament com os argumentos de
+// linha de comando, exibindo o resultado de cada partida e a tabela final
+func RunTournamentCommand(out io.Writer) error {
+	var names []string
+	var players []PlayerFactory
+	for _, spec := range strings.Split(viper.GetString(PLAYERS), ";") {
+		if spec = strings.TrimSpace(spec); spec != "" {
+			names = append(names, spec)
+			players = append(players, QuietPlayerFactory(spec))
+		}
+	}
+	timeControl, err := TimeControlFlag()
+	if err != nil {
+		return err
+	}
+
+	var openings []*chess.Game
+	if path := viper.GetString(OPENINGS); path != "" {
+		if openings, err = LoadOpenings(path); err != nil {
+			return err
+		}
+	}
+	// Sem um arquivo de saída, o PGN com todas as partidas é exibido ao fim
+	// do torneio
+	var pgnOut io.Writer
+	if path := viper.GetString(PGN_OUT); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		pgnOut = f
+	}
+
+	tournament := &Tournament{
+		Names:           names,
+		Players:         players,
+		Format:          viper.GetString(FORMAT),
+		GamesPerPairing: viper.GetInt(PAIRING_GAMES),
+		Openings:        openings,
+		Event:           "puc-chess tournament",
+		Concurrency:     viper.GetInt(CONCURRENCY),
+		Seed:            viper.GetInt64(SEED),
+		TimeControl:     timeControl,
+		OnGameEnd: func(game *chess.Game, white, black int) {
+			fmt.Fprintf(out, "Game %s: %s - %s %s {%s}\n", game.GetTagPair("Round").Value,
+				names[white], names[black], game.Outcome(), describeOutcome(game))
+		},
+	}
+	fmt.Fprintf(out, "Tournament: %s, %d players, %d games per pairing%s\n", tournament.Format, len(names), tournament.GamesPerPairing, describeTimeControl(timeControl))
+	result, err := tournament.Run(context.Background())
+	if result == nil {
+		return err
+	}
+	fmt.Fprintln(out)
+	result.PrintCrosstable(out)
+
+	if pgnOut == nil {
+		fmt.Fprintln(out)
+		pgnOut = out
+	}
+	for _, game := range result.Games {
+		fmt.Fprintf(pgnOut, "%s\n\n", game)
+	}
+	return err
+}
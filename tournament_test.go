@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestTournament(t *testing.T) {
+	tests := []struct {
+		format string
+		pairs  [][2]int
+	}{
+		{RoundRobinFormat, [][2]int{{0, 1}, {0, 2}, {0, 3}, {1, 2}, {1, 3}, {2, 3}}},
+		{GauntletFormat, [][2]int{{0, 1}, {0, 2}, {0, 3}}},
+	}
+	for _, tt := range tests {
+		colors := make(map[int][2]int)
+		tournament := &Tournament{
+			Names:           []string{"A", "B", "C", "D"},
+			Players:         []PlayerFactory{randomFactory, randomFactory, randomFactory, randomFactory},
+			Format:          tt.format,
+			GamesPerPairing: 2,
+			Openings:        []*chess.Game{rookEndgame},
+			Concurrency:     2,
+			Seed:            3,
+			OnGameEnd: func(game *chess.Game, white, black int) {
+				round, _ := strconv.Atoi(game.GetTagPair("Round").Value)
+				colors[round] = [2]int{white, black}
+			},
+		}
+		result, err := tournament.Run(context.Background())
+		if err != nil {
+			t.Fatalf("%s: Run failed: %v", tt.format, err)
+		}
+
+		want := 2 * len(tt.pairs)
+		if len(result.Games) != want || len(colors) != want {
+			t.Fatalf("%s: %d games were played and %d reported, want %d", tt.format, len(result.Games), len(colors), want)
+		}
+
+		// As partidas estão em ordem de rodada e cada confronto troca as
+		// cores na segunda partida
+		for i, game := range result.Games {
+			pair := tt.pairs[i/2]
+			if i%2 == 1 {
+				pair[0], pair[1] = pair[1], pair[0]
+			}
+			if round := game.GetTagPair("Round").Value; round != strconv.Itoa(i+1) {
+				t.Errorf("%s: game %d is round %s", tt.format, i+1, round)
+			}
+			if got := colors[i+1]; got != pair {
+				t.Errorf("%s: round %d is %v as white and black, want %v", tt.format, i+1, got, pair)
+			}
+		}
+
+		// O placar de cada confronto é o mesmo visto pelos dois lados
+		paired := make(map[[2]int]bool)
+		for _, pair := range tt.pairs {
+			paired[pair] = true
+			paired[[2]int{pair[1], pair[0]}] = true
+		}
+		for i := range result.Scores {
+			for j := range result.Scores[i] {
+				score := result.Scores[i][j]
+				if score != result.Scores[j][i].Reverse() {
+					t.Errorf("%s: %s vs %s is %+v, but %s vs %s is %+v", tt.format,
+						result.Names[i], result.Names[j], score, result.Names[j], result.Names[i], result.Scores[j][i])
+				}
+				wantGames := 0
+				if paired[[2]int{i, j}] {
+					wantGames = tournament.GamesPerPairing
+				}
+				if score.Games() != wantGames {
+					t.Errorf("%s: %s vs %s has %d games, want %d", tt.format, result.Names[i], result.Names[j], score.Games(), wantGames)
+				}
+			}
+		}
+
+		// A classificação tem todos os jogadores, do maior para o menor
+		// placar
+		ranking := result.Ranking()
+		seen := make(map[int]bool)
+		for i, player := range ranking {
+			seen[player] = true
+			if i > 0 && result.Total(player).Points() > result.Total(ranking[i-1]).Points() {
+				t.Errorf("%s: the ranking %v is not sorted by points", tt.format, ranking)
+			}
+		}
+		if len(seen) != len(tournament.Names) {
+			t.Errorf("%s: the ranking %v does not have every player once", tt.format, ranking)
+		}
+	}
+}
+
+func TestTournamentCrosstable(t *testing.T) {
+	// B vence A duas vezes e empata com C, enquanto A e C dividem os pontos
+	result := &TournamentResult{
+		Names: []string{"A", "B", "C"},
+		Scores: [][]MatchScore{
+			{{}, {Losses: 2}, {Wins: 1, Draws: 1}},
+			{{Wins: 2}, {}, {Draws: 2}},
+			{{Draws: 1, Losses: 1}, {Draws: 2}, {}},
+		},
+	}
+	// A e C têm a mesma pontuação e mantêm a ordem em que foram informados
+	ranking := result.Ranking()
+	if fmt.Sprint(ranking) != "[1 0 2]" {
+		t.Errorf("Ranking = %v, want [1 0 2]", ranking)
+	}
+
+	var out bytes.Buffer
+	result.PrintCrosstable(&out)
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("the crosstable should have a header, a separator and 3 players, got:\n%s", out.String())
+	}
+	// As colunas dos confrontos seguem a classificação: B, A e C
+	rows := []struct {
+		name, score string
+		cells       []string
+	}{
+		{"B", "3.0/4", []string{"-", "2.0/2", "1.0/2"}},
+		{"A", "1.5/4", []string{"0.0/2", "-", "1.5/2"}},
+		{"C", "1.5/4", []string{"1.0/2", "0.5/2", "-"}},
+	}
+	for i, row := range rows {
+		line := lines[i+2]
+		fields := strings.Fields(line)
+		if fields[0] != fmt.Sprint(i+1) || fields[1] != row.name || fields[2] != row.score {
+			t.Errorf("rank %d is %q, want %s with %s", i+1, line, row.name, row.score)
+		}
+		if got := fields[len(fields)-3:]; strings.Join(got, " ") != strings.Join(row.cells, " ") {
+			t.Errorf("the pairings of %s are %v, want %v", row.name, got, row.cells)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"hash/fnv"
+	"unsafe"
+
+	"github.com/notnil/chess"
+)
+
+// Bound indica como o valor guardado na tabela de transposição se relaciona
+// com a avaliação real da posição
+type Bound uint8
+
+const (
+	// BoundExact indica que o valor é a avaliação exata da posição
+	BoundExact Bound = iota + 1
+	// BoundLower indica que a avaliação real é maior ou igual ao valor
+	BoundLower
+	// BoundUpper indica que a avaliação real é menor ou igual ao valor
+	BoundUpper
+)
+
+// ttEntry representa uma posição guardada na tabela de transposição
+type ttEntry struct {
+	key   uint64
+	move  *chess.Move
+	score int32
+	depth int8
+	bound Bound
+}
+
+// TranspositionTable guarda o resultado da busca em posições já visitadas,
+// de forma que posições alcançadas por diferentes ordens de jogadas não
+// precisem ser avaliadas novamente
+type TranspositionTable struct {
+	entries []ttEntry
+	mask    uint64
+}
+
+// NewTranspositionTable cria uma tabela de transposição que ocupa no máximo
+// a quantidade de megabytes informada
+func NewTranspositionTable(sizeMB int) *TranspositionTable {
+	if sizeMB < 1 {
+		sizeMB = 1
+	}
+	// O número de entradas é arredondado para uma potência de dois para que
+	// o índice possa ser calculado com uma máscara
+	count := uint64(sizeMB) * 1024 * 1024 / uint64(unsafe.Sizeof(ttEntry{}))
+	size := uint64(1)
+	for size*2 <= count {
+		size *= 2
+	}
+	return &TranspositionTable{
+		entries: make([]ttEntry, size),
+		mask:    size - 1,
+	}
+}
+
+// Probe procura uma posição na tabela
+func (tt *TranspositionTable) Probe(key uint64) (ttEntry, bool) {
+	entry := tt.entries[key&tt.mask]
+	if entry.bound == 0 || entry.key != key {
+		return ttEntry{}, false
+	}
+	return entry, true
+}
+
+// Store guarda o resultado da busca de uma posição na tabela. Uma entrada
+// da mesma posição buscada com maior profundidade não é substituída
+func (tt *TranspositionTable) Store(key uint64, depth, score int, bound Bound, move *chess.Move) {
+	entry := &tt.entries[key&tt.mask]
+	if entry.key == key && int(entry.depth) > depth {
+		return
+	}
+	// Mantém a melhor jogada anterior caso a busca atual não tenha
+	// encontrado nenhuma
+	if move == nil && entry.key == key {
+		move = entry.move
+	}
+	*entry = ttEntry{
+		key:   key,
+		move:  move,
+		score: int32(score),
+		depth: int8(depth),
+		bound: bound,
+	}
+}
+
+// Clear apaga todas as posições guardadas na tabela
+func (tt *TranspositionTable) Clear() {
+	for i := range tt.entries {
+		tt.entries[i] = ttEntry{}
+	}
+}
+
+// positionKey calcula a chave de uma posição na tabela de transposição. O
+// Position.Hash inclui os contadores de jogadas, o que faria com que a mesma
+// posição alcançada por ordens diferentes tivesse chaves diferentes, então
+// aqui são considerados apenas o tabuleiro, a vez, os roques e o en passant
+func positionKey(pos *chess.Position) uint64 {
+	data, _ := pos.MarshalBinary()
+	h := fnv.New64a()
+	// Os 96 primeiros bytes são o tabuleiro, seguidos de um byte para as
+	// meias-jogadas, dois para o número da jogada, um para o en passant e
+	// um para os roques e a vez
+	h.Write(data[:96])
+	h.Write(data[99:])
+	return h.Sum64()
+}
+
+// scoreToTT ajusta uma avaliação de mate para ser guardada na tabela. Na
+// busca os mates são medidos a partir da raiz, mas na tabela eles precisam
+// ser medidos a partir da posição guardada, que pode ser alcançada em
+// outros plies
+func scoreToTT(score, ply int) int {
+	if score >= maxMateScore {
+		return score + ply
+	} else if score <= -maxMateScore {
+		return score - ply
+	}
+	return score
+}
+
+// scoreFromTT desfaz o ajuste feito por scoreToTT
+func scoreFromTT(score, ply int) int {
+	if score >= maxMateScore {
+		return score - ply
+	} else if score <= -maxMateScore {
+		return score + ply
+	}
+	return score
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// gameFromFEN cria uma partida a partir do FEN informado
+func gameFromFEN(t *testing.T, fen string) *chess.Game {
+	t.Helper()
+	option, err := chess.FEN(fen)
+	if err != nil {
+		t.Fatalf("invalid FEN %q: %v", fen, err)
+	}
+	return chess.NewGame(option)
+}
+
+// gameAfter cria uma partida com as jogadas informadas, em notação algébrica
+func gameAfter(t *testing.T, moves ...string) *chess.Game {
+	t.Helper()
+	game := chess.NewGame()
+	for _, move := range moves {
+		if err := game.MoveStr(move); err != nil {
+			t.Fatalf("invalid move %s: %v", move, err)
+		}
+	}
+	return game
+}
+
+func TestPositionKeyTransposition(t *testing.T) {
+	a := gameAfter(t, "Nf3", "Nf6", "Nc3").Position()
+	b := gameAfter(t, "Nc3", "Nf6", "Nf3").Position()
+	if positionKey(a) != positionKey(b) {
+		t.Error("the same position reached by different move orders has different keys")
+	}
+}
+
+func TestPositionKeyIgnoresMoveCounters(t *testing.T) {
+	a := gameFromFEN(t, "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3").Position()
+	b := gameFromFEN(t, "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 17 42").Position()
+	if positionKey(a) != positionKey(b) {
+		t.Error("positions that differ only in the move counters have different keys")
+	}
+}
+
+func TestPositionKeyDistinguishesState(t *testing.T) {
+	// O tabuleiro, a vez, os roques e o en passant precisam fazer parte da
+	// chave
+	base := "rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+	others := []string{
+		"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+		"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b Kkq e3 0 1",
+		"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPPNPPP/RNBQKB1R b KQkq - 0 1",
+	}
+	key := positionKey(gameFromFEN(t, base).Position())
+	for _, fen := range others {
+		if positionKey(gameFromFEN(t, fen).Position()) == key {
+			t.Errorf("%q has the same key as %q", fen, base)
+		}
+	}
+}
+
+func TestMateScoreThroughTT(t *testing.T) {
+	// Um mate em 2 plies a partir de um nó no ply 3 vale mateScore-5 na
+	// raiz. Quando a mesma posição é encontrada no ply 1 ele vale
+	// mateScore-3
+	tests := []struct {
+		score, storePly, probePly, want int
+	}{
+		{mateScore - 5, 3, 1, mateScore - 3},
+		{-mateScore + 5, 3, 1, -mateScore + 3},
+		{mateScore - 3, 1, 4, mateScore - 6},
+		{-mateScore + 3, 1, 4, -mateScore + 6},
+		{250, 3, 1, 250},
+	}
+	for _, tt := range tests {
+		table := NewTranspositionTable(1)
+		table.Store(1, 4, scoreToTT(tt.score, tt.storePly), BoundExact, nil)
+		entry, ok := table.Probe(1)
+		if !ok {
+			t.Fatal("the stored entry was not found")
+		}
+		if got := scoreFromTT(int(entry.score), tt.probePly); got != tt.want {
+			t.Errorf("score %d stored at ply %d and probed at ply %d = %d, want %d",
+				tt.score, tt.storePly, tt.probePly, got, tt.want)
+		}
+	}
+}
+
+func TestTTKeepsDeeperEntry(t *testing.T) {
+	table := NewTranspositionTable(1)
+	table.Store(42, 6, 100, BoundExact, nil)
+	table.Store(42, 3, -100, BoundUpper, nil)
+	entry, ok := table.Probe(42)
+	if !ok {
+		t.Fatal("the stored entry was not found")
+	}
+	if entry.depth != 6 || entry.score != 100 || entry.bound != BoundExact {
+		t.Errorf("a shallower entry replaced a deeper one: %+v", entry)
+	}
+
+	// Uma busca mais profunda da mesma posição substitui a entrada
+	table.Store(42, 8, 50, BoundLower, nil)
+	if entry, _ := table.Probe(42); entry.depth != 8 || entry.score != 50 {
+		t.Errorf("a deeper entry did not replace a shallower one: %+v", entry)
+	}
+
+	// Outra posição com o mesmo índice sempre substitui a entrada
+	other := 42 + uint64(len(table.entries))
+	table.Store(other, 1, 7, BoundExact, nil)
+	if _, ok := table.Probe(42); ok {
+		t.Error("the old position is still found after another one took its slot")
+	}
+	if entry, ok := table.Probe(other); !ok || entry.score != 7 {
+		t.Errorf("the new position was not stored: %+v", entry)
+	}
+}
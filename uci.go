@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// uciSession guarda o estado de uma conversa com uma interface gráfica
+// através do protocolo UCI (Universal Chess Interface)
+type uciSession struct {
+	engine *Engine
+	game   *chess.Game
+
+	outMu sync.Mutex
+	out   io.Writer
+
+	// cancel interrompe a busca em andamento e done é fechado quando ela
+	// termina de enviar o bestmove. infinite indica que a busca só termina
+	// com o comando stop
+	cancel   context.CancelFunc
+	done     chan struct{}
+	infinite bool
+}
+
+// RunUCI executa o motor no modo UCI, lendo os comandos da entrada e
+// escrevendo as respostas na saída informadas, até receber o comando quit
+// ou a entrada terminar. Quando a entrada termina, uma busca com limites
+// ainda pode terminar e enviar o bestmove, o que permite usar uma entrada
+// escrita de antemão
+func RunUCI(in io.Reader, out io.Writer, engine *Engine) error {
+	s := &uciSession{
+		engine: engine,
+		game:   chess.NewGame(),
+		out:    out,
+	}
+	defer s.stop()
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			s.send("id name puc-chess")
+			s.send("id author zignd")
+			s.send("option name Hash type spin default %d min 1 max 4096", defaultHashMB)
+			s.send("option name Contempt type spin default 0 min -1000 max 1000")
+			s.send("option name Eval type combo default %s var %s var %s", MaterialEvaluatorName, MaterialEvaluatorName, PositionalEvaluatorName)
+			s.send("uciok")
+		case "isready":
+			s.send("readyok")
+		case "setoption":
+			s.stop()
+			if err := s.setOption(fields[1:]); err != nil {
+				s.send("info string %s", err)
+			}
+		case "ucinewgame":
+			s.stop()
+			s.engine.NewGame()
+			s.game = chess.NewGame()
+		case "position":
+			s.stop()
+			game, err := uciPosition(fields[1:])
+			if err != nil {
+				s.send("info string %s", err)
+				continue
+			}
+			s.game = game
+		case "go":
+			s.stop()
+			s.goSearch(fields[1:])
+		case "stop":
+			s.stop()
+		case "quit":
+			return nil
+		}
+	}
+	s.wait()
+	return scanner.Err()
+}
+
+// send escreve uma linha na saída. A busca roda em outra goroutine, então
+// a escrita é protegida para que as linhas não se misturem
+func (s *uciSession) send(format string, args ...interface{}) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	fmt.Fprintf(s.out, format+"\n", args...)
+}
+
+// setOption trata o comando "setoption name <nome> value <valor>"
+func (s *uciSession) setOption(args []string) error {
+	var name, value []string
+	target := &name
+	for _, arg := range args {
+		switch arg {
+		case "name":
+			target = &name
+		case "value":
+			target = &value
+		default:
+			*target = append(*target, arg)
+		}
+	}
+	v := strings.Join(value, " ")
+	switch strings.ToLower(strings.Join(name, " ")) {
+	case "hash":
+		mb, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid Hash value %q", v)
+		}
+		s.engine.SetHashSize(mb)
+	case "contempt":
+		contempt, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid Contempt value %q", v)
+		}
+		s.engine.Contempt = contempt
+	case "eval":
+		evaluator, err := NewEvaluator(v)
+		if err != nil {
+			return err
+		}
+		s.engine.Evaluator = evaluator
+	default:
+		return fmt.Errorf("unknown option %q", strings.Join(name, " "))
+	}
+	return nil
+}
+
+// uciPosition trata os argumentos do comando
+// "position [startpos | fen <fen>] [moves <jogadas>...]"
+func uciPosition(args []string) (*chess.Game, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("missing position")
+	}
+	var game *chess.Game
+	var moves []string
+	switch args[0] {
+	case "startpos":
+		game = chess.NewGame()
+		args = args[1:]
+	case "fen":
+		end := len(args)
+		for i, arg := range args {
+			if arg == "moves" {
+				end = i
+				break
+			}
+		}
+		fen, err := chess.FEN(strings.Join(args[1:end], " "))
+		if err != nil {
+			return nil, err
+		}
+		game = chess.NewGame(fen)
+		args = args[end:]
+	default:
+		return nil, fmt.Errorf("invalid position %q", args[0])
+	}
+	if len(args) > 0 && args[0] == "moves" {
+		moves = args[1:]
+	}
+	for _, moveStr := range moves {
+		if err := MoveUCI(game, moveStr); err != nil {
+			return nil, err
+		}
+	}
+	return game, nil
+}
+
+// MoveUCI faz no tabuleiro uma jogada escrita na notação UCI, como "e2e4"
+// ou "e7e8q"
+func MoveUCI(game *chess.Game, moveStr string) error {
+	move, err := chess.UCINotation{}.Decode(game.Position(), moveStr)
+	if err != nil {
+		return err
+	}
+	return game.Move(move)
+}
+
+// goSearch trata os argumentos do comando go e inicia a busca em uma nova
+// goroutine, para que comandos como stop continuem sendo lidos
+func (s *uciSession) goSearch(args []string) {
+	limits := SearchLimits{}
+	infinite := false
+	var wtime, btime, winc, binc time.Duration
+	movesToGo := 0
+	for i := 0; i < len(args); i++ {
+		// Quase todos os parâmetros são seguidos de um número
+		value := 0
+		if i+1 < len(args) {
+			value, _ = strconv.Atoi(args[i+1])
+		}
+		switch args[i] {
+		case "infinite":
+			infinite = true
+			continue
+		case "depth":
+			limits.Depth = value
+		case "movetime":
+			limits.MoveTime = time.Duration(value) * time.Millisecond
+		case "wtime":
+			wtime = time.Duration(value) * time.Millisecond
+		case "btime":
+			btime = time.Duration(value) * time.Millisecond
+		case "winc":
+			winc = time.Duration(value) * time.Millisecond
+		case "binc":
+			binc = time.Duration(value) * time.Millisecond
+		case "movestogo":
+			movesToGo = value
+		default:
+			continue
+		}
+		i++
+	}
+
+	// Com o relógio informado, o tempo da jogada é uma fração do tempo
+	// restante de quem joga
+	remaining, increment := wtime, winc
+	if s.game.Position().Turn() == chess.Black {
+		remaining, increment = btime, binc
+	}
+	if limits.MoveTime == 0 && remaining > 0 {
+		limits.MoveTime = AllocateMoveTime(remaining, increment, movesToGo)
+	}
+	// Sem nenhum limite a busca só termina com o comando stop
+	if limits.Depth == 0 && limits.MoveTime == 0 {
+		infinite = true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	s.infinite = infinite
+	game := s.game
+	done := s.done
+	s.engine.OnIteration = func(r *SearchResult) {
+		nps := int64(0)
+		if r.Time > 0 {
+			nps = int64(float64(r.Nodes) / r.Time.Seconds())
+		}
+		s.send("info depth %d score %s nodes %d nps %d time %d pv %s",
+			r.Depth, uciScore(s.engine.Evaluator, r.Score), r.Nodes, nps, r.Time.Milliseconds(), FormatMoves(r.PV))
+	}
+
+	go func() {
+		defer close(done)
+		result, err := s.engine.IterativeDeepening(ctx, game, limits)
+		// Em uma busca infinita o bestmove só pode ser enviado depois do
+		// comando stop, mesmo que a busca termine antes
+		if infinite {
+			<-ctx.Done()
+		}
+		if err != nil || result == nil {
+			s.send("bestmove 0000")
+			return
+		}
+		s.send("bestmove %s", result.Move)
+	}()
+}
+
+// stop interrompe a busca em andamento, se houver, e aguarda o envio do
+// bestmove
+func (s *uciSession) stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+	s.cancel = nil
+}
+
+// wait aguarda a busca em andamento terminar, interrompendo-a se ela for
+// infinita
+func (s *uciSession) wait() {
+	if s.cancel != nil && !s.infinite {
+		<-s.done
+	}
+	s.stop()
+}
+
+// uciScore converte a avaliação da busca para o formato do protocolo UCI,
+// em centipeões, em que os mates são informados em jogadas, negativas quando
+// quem joga leva o mate
+func uciScore(evaluator Evaluator, score int) string {
+	if score >= maxMateScore {
+		return fmt.Sprintf("mate %d", (mateScore-score+1)/2)
+	} else if score <= -maxMateScore {
+		return fmt.Sprintf("mate %d", -(mateScore+score)/2)
+	}
+	return fmt.Sprintf("cp %d", Centipawns(evaluator, score))
+}
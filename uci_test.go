@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestRunUCI(t *testing.T) {
+	in := strings.NewReader(strings.Join([]string{
+		"uci",
+		"isready",
+		"position startpos moves e2e4 e7e5 g1f3",
+		"go depth 3",
+	}, "\n") + "\n")
+	var out bytes.Buffer
+	if err := RunUCI(in, &out, NewEngine(1)); err != nil {
+		t.Fatalf("RunUCI returned an error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	has := func(prefix string) bool {
+		for _, line := range lines {
+			if strings.HasPrefix(line, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, prefix := range []string{"id name puc-chess", "uciok", "readyok", "info depth 1 ", "info depth 3 "} {
+		if !has(prefix) {
+			t.Errorf("missing a line starting with %q in the output:\n%s", prefix, out.String())
+		}
+	}
+
+	// O bestmove é a última linha e precisa ser uma jogada válida das pretas
+	last := strings.Fields(lines[len(lines)-1])
+	if len(last) != 2 || last[0] != "bestmove" {
+		t.Fatalf("the last line should be the bestmove, got %q", lines[len(lines)-1])
+	}
+	game, err := uciPosition(strings.Fields("startpos moves e2e4 e7e5 g1f3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := MoveUCI(game, last[1]); err != nil {
+		t.Errorf("bestmove %s is not legal: %v", last[1], err)
+	}
+	if game.Position().Turn() != chess.White {
+		t.Errorf("bestmove %s should have been played by black", last[1])
+	}
+}
+
+func TestRunUCIStop(t *testing.T) {
+	// Sem limites a busca só termina com o stop, que precisa enviar o
+	// bestmove
+	in := strings.NewReader("position startpos\ngo infinite\nstop\nquit\n")
+	var out bytes.Buffer
+	if err := RunUCI(in, &out, NewEngine(1)); err != nil {
+		t.Fatalf("RunUCI returned an error: %v", err)
+	}
+	if !strings.Contains(out.String(), "bestmove ") {
+		t.Errorf("missing the bestmove after stop in the output:\n%s", out.String())
+	}
+}
+
+func TestUCIScore(t *testing.T) {
+	tests := []struct {
+		evaluator Evaluator
+		score     int
+		want      string
+	}{
+		{MaterialEvaluator{}, 10, "cp 100"},
+		{MaterialEvaluator{}, -35, "cp -350"},
+		{NewPositionalEvaluator(), 82, "cp 82"},
+		{MaterialEvaluator{}, mateScore - 1, "mate 1"},
+		{MaterialEvaluator{}, -mateScore + 2, "mate -1"},
+	}
+	for _, tt := range tests {
+		if got := uciScore(tt.evaluator, tt.score); got != tt.want {
+			t.Errorf("uciScore(%T, %d) = %q, want %q", tt.evaluator, tt.score, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// defaultUCIEngineTimeout é o tempo máximo de espera pelas respostas de um
+// motor externo, além do tempo que ele tem para pensar
+const defaultUCIEngineTimeout = 5 * time.Second
+
+// errUCIEngineTimeout indica que o motor externo não respondeu a tempo
+var errUCIEngineTimeout = errors.New("uci engine did not answer in time")
+
+// UCIEngine controla um motor de xadrez externo, executado como um
+// subprocesso e controlado através do protocolo UCI
+type UCIEngine struct {
+	// Name é o nome informado pelo motor em resposta ao comando uci
+	Name string
+	// Timeout é o tempo máximo de espera pelas respostas do motor, além do
+	// tempo que ele tem para pensar em cada jogada
+	Timeout time.Duration
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string
+	// closed é fechado pelo Close, liberando a goroutine que lê as
+	// respostas caso ninguém mais as esteja lendo
+	closed chan struct{}
+	// pending é o número de buscas cujo bestmove ainda não foi recebido,
+	// como as que não terminaram a tempo
+	pending int
+}
+
+// StartUCIEngine executa o motor externo do caminho informado, configura as
+// opções informadas e aguarda até que ele esteja pronto para jogar
+func StartUCIEngine(path string, options map[string]string) (*UCIEngine, error) {
+	cmd := exec.Command(path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start uci engine %s: %w", path, err)
+	}
+
+	u := &UCIEngine{
+		Name:    path,
+		Timeout: defaultUCIEngineTimeout,
+		cmd:     cmd,
+		stdin:   stdin,
+		lines:   make(chan string, 64),
+		closed:  make(chan struct{}),
+	}
+	// As respostas são lidas em outra goroutine para que a espera por elas
+	// possa ter um tempo limite
+	go func() {
+		defer close(u.lines)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			select {
+			case u.lines <- scanner.Text():
+			case <-u.closed:
+				return
+			}
+		}
+	}()
+
+	if err := u.send("uci"); err != nil {
+		u.Close()
+		return nil, err
+	}
+	err = u.waitFor(u.Timeout, func(line string) bool {
+		if name := strings.TrimPrefix(line, "id name "); name != line {
+			u.Name = name
+		}
+		return line == "uciok"
+	})
+	if err != nil {
+		u.Close()
+		return nil, err
+	}
+	for name, value := range options {
+		if err := u.send("setoption name %s value %s", name, value); err != nil {
+			u.Close()
+			return nil, err
+		}
+	}
+	if err := u.ready(); err != nil {
+		u.Close()
+		return nil, err
+	}
+	return u, nil
+}
+
+// ParseUCIEngineOptions converte opções no formato "Nome=Valor,Nome=Valor"
+func ParseUCIEngineOptions(s string) (map[string]string, error) {
+	return parseOptions(s)
+}
+
+// parseOptions converte uma lista de opções no formato "nome=valor",
+// separadas por vírgulas, em um mapa
+func parseOptions(s string) (map[string]string, error) {
+	options := map[string]string{}
+	for _, option := range strings.Split(s, ",") {
+		if strings.TrimSpace(option) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(option, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid option %q, it should be like 'Name=Value'", option)
+		}
+		options[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return options, nil
+}
+
+// NewGame avisa o motor de que uma nova partida vai começar
+func (u *UCIEngine) NewGame() error {
+	if err := u.send("ucinewgame"); err != nil {
+		return err
+	}
+	return u.ready()
+}
+
+// BestMove envia a posição atual da partida para o motor e retorna a
+// jogada escolhida por ele dentro do tempo informado. Os bestmove atrasados
+// de buscas anteriores, que não terminaram a tempo, são descartados
+func (u *UCIEngine) BestMove(game *chess.Game, moveTime time.Duration) (*chess.Move, error) {
+	u.discardLines()
+	if err := u.send("position %s", uciPositionCommand(game)); err != nil {
+		return nil, err
+	}
+	if err := u.send("go movetime %d", moveTime.Milliseconds()); err != nil {
+		return nil, err
+	}
+	u.pending++
+
+	// O motor responde às buscas em ordem, então a resposta desta busca é o
+	// bestmove recebido quando não há mais nenhum pendente
+	var moveStr string
+	wait := func(line string) bool {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "bestmove" && u.pending == 0 {
+			moveStr = fields[1]
+			return true
+		}
+		return false
+	}
+	if err := u.waitFor(moveTime+u.Timeout, wait); err != nil {
+		// Pede para o motor parar e dá a ele mais uma chance de responder
+		if err != errUCIEngineTimeout || u.send("stop") != nil || u.waitFor(u.Timeout, wait) != nil {
+			return nil, err
+		}
+	}
+
+	move, err := chess.UCINotation{}.Decode(game.Position(), moveStr)
+	if err != nil {
+		return nil, fmt.Errorf("uci engine %s sent an invalid move %q: %w", u.Name, moveStr, err)
+	}
+	if valid := findValidMove(game, move); valid != nil {
+		return valid, nil
+	}
+	return nil, fmt.Errorf("uci engine %s sent an illegal move %q", u.Name, moveStr)
+}
+
+// Close encerra o motor, pedindo para ele sair e matando o processo caso
+// ele não saia a tempo
+func (u *UCIEngine) Close() error {
+	close(u.closed)
+	u.send("quit")
+	u.stdin.Close()
+	exited := make(chan error, 1)
+	go func() {
+		exited <- u.cmd.Wait()
+	}()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(u.Timeout):
+		u.cmd.Process.Kill()
+		return <-exited
+	}
+}
+
+// send escreve um comando na entrada do motor
+func (u *UCIEngine) send(format string, args ...interface{}) error {
+	_, err := fmt.Fprintf(u.stdin, format+"\n", args...)
+	return err
+}
+
+// ready aguarda até que o motor tenha processado os comandos anteriores
+func (u *UCIEngine) ready() error {
+	if err := u.send("isready"); err != nil {
+		return err
+	}
+	return u.waitFor(u.Timeout, func(line string) bool {
+		return line == "readyok"
+	})
+}
+
+// discardLines descarta as respostas já recebidas do motor, contando os
+// bestmove atrasados
+func (u *UCIEngine) discardLines() {
+	for {
+		select {
+		case line, ok := <-u.lines:
+			if !ok {
+				return
+			}
+			u.receive(strings.TrimSpace(line))
+		default:
+			return
+		}
+	}
+}
+
+// receive conta os bestmove recebidos do motor
+func (u *UCIEngine) receive(line string) {
+	if u.pending > 0 && strings.HasPrefix(line, "bestmove ") {
+		u.pending--
+	}
+}
+
+// waitFor lê as respostas do motor até que uma delas satisfaça a condição
+// informada ou o tempo limite seja atingido
+func (u *UCIEngine) waitFor(timeout time.Duration, done func(line string) bool) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-u.lines:
+			if !ok {
+				return fmt.Errorf("uci engine %s exited unexpectedly", u.Name)
+			}
+			line = strings.TrimSpace(line)
+			u.receive(line)
+			if done(line) {
+				return nil
+			}
+		case <-timer.C:
+			return errUCIEngineTimeout
+		}
+	}
+}
+
+// uciPositionCommand monta os argumentos do comando position que
+// representam a partida informada
+func uciPositionCommand(game *chess.Game) string {
+	position := "startpos"
+	if start := game.Positions()[0].String(); start != chess.StartingPosition().String() {
+		position = "fen " + start
+	}
+	moves := game.Moves()
+	if len(moves) == 0 {
+		return position
+	}
+	return position + " moves " + FormatMoves(moves)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// fakeEngineEnv é a variável de ambiente que faz o executável dos testes se
+// comportar como um motor UCI, com o comportamento indicado pelo seu valor
+const fakeEngineEnv = "PUC_CHESS_FAKE_ENGINE"
+
+// Comportamentos do motor falso
+const (
+	// fakeEngineNormal responde a cada go com a primeira jogada válida
+	fakeEngineNormal = "normal"
+	// fakeEngineLate não responde à primeira busca e só envia o bestmove
+	// dela ao receber a busca seguinte
+	fakeEngineLate = "late"
+	// fakeEngineHang responde ao handshake, mas depois ignora todos os
+	// comandos, inclusive o quit
+	fakeEngineHang = "hang"
+)
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(fakeEngineEnv); mode != "" {
+		runFakeEngine(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeEngine executa o motor falso na entrada e na saída padrão
+func runFakeEngine(mode string) {
+	game := chess.NewGame()
+	var late string
+	searches := 0
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			fmt.Println("id name Fake Engine")
+			fmt.Println("option name Hash type spin default 1 min 1 max 16")
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "position":
+			game, _ = uciPosition(fields[1:])
+		case "go":
+			searches++
+			best := game.ValidMoves()[0].String()
+			switch {
+			case mode == fakeEngineHang:
+			case mode == fakeEngineLate && searches == 1:
+				late = best
+			default:
+				if late != "" {
+					fmt.Println("bestmove", late)
+					late = ""
+				}
+				fmt.Println("info depth 1 score cp 0")
+				fmt.Println("bestmove", best)
+			}
+		case "quit":
+			if mode != fakeEngineHang {
+				return
+			}
+		}
+	}
+	if mode == fakeEngineHang {
+		time.Sleep(time.Hour)
+	}
+}
+
+// startFakeEngine executa o próprio executável dos testes como um motor UCI
+// com o comportamento informado
+func startFakeEngine(t *testing.T, mode string) *UCIEngine {
+	t.Helper()
+	t.Setenv(fakeEngineEnv, mode)
+	path, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := StartUCIEngine(path, map[string]string{"Hash": "1"})
+	if err != nil {
+		t.Fatalf("StartUCIEngine failed: %v", err)
+	}
+	return u
+}
+
+// assertReaderStops verifica que a goroutine que lê as respostas do motor
+// terminou depois do Close
+func assertReaderStops(t *testing.T, u *UCIEngine) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-u.lines:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("the goroutine reading the engine output is still running after Close")
+		}
+	}
+}
+
+func TestUCIEngine(t *testing.T) {
+	u := startFakeEngine(t, fakeEngineNormal)
+	if u.Name != "Fake Engine" {
+		t.Errorf("Name = %q, want %q", u.Name, "Fake Engine")
+	}
+	if err := u.NewGame(); err != nil {
+		t.Fatalf("NewGame failed: %v", err)
+	}
+
+	game := chess.NewGame()
+	for i := 0; i < 4; i++ {
+		move, err := u.BestMove(game, 10*time.Millisecond)
+		if err != nil {
+			t.Fatalf("BestMove failed: %v", err)
+		}
+		if want := game.ValidMoves()[0]; move.String() != want.String() {
+			t.Fatalf("BestMove = %s, want %s", move, want)
+		}
+		if err := game.Move(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := u.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	assertReaderStops(t, u)
+}
+
+func TestUCIEngineDiscardsLateBestMove(t *testing.T) {
+	u := startFakeEngine(t, fakeEngineLate)
+	defer u.Close()
+	u.Timeout = 100 * time.Millisecond
+
+	game := chess.NewGame()
+	if _, err := u.BestMove(game, 10*time.Millisecond); err != errUCIEngineTimeout {
+		t.Fatalf("BestMove error = %v, want %v", err, errUCIEngineTimeout)
+	}
+
+	// O bestmove atrasado da primeira busca é uma jogada das brancas, que
+	// não é válida na posição da segunda busca
+	game.Move(game.ValidMoves()[0])
+	move, err := u.BestMove(game, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("BestMove after a timeout failed: %v", err)
+	}
+	if want := game.ValidMoves()[0]; move.String() != want.String() {
+		t.Errorf("BestMove = %s, want %s", move, want)
+	}
+}
+
+func TestUCIEngineHang(t *testing.T) {
+	u := startFakeEngine(t, fakeEngineHang)
+	u.Timeout = 100 * time.Millisecond
+
+	if _, err := u.BestMove(chess.NewGame(), 10*time.Millisecond); err != errUCIEngineTimeout {
+		t.Fatalf("BestMove error = %v, want %v", err, errUCIEngineTimeout)
+	}
+
+	// O motor ignora o quit, então o Close precisa matar o processo
+	closed := make(chan struct{})
+	go func() {
+		u.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not kill the hanging engine")
+	}
+	assertReaderStops(t, u)
+}
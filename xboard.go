@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// xboardSession guarda o estado de uma conversa com uma interface gráfica
+// através do protocolo XBoard, também conhecido como Chess Engine
+// Communication Protocol (CECP)
+type xboardSession struct {
+	engine *Engine
+	game   *GameHistory
+
+	// A busca escreve o que está pensando em outra goroutine, então a
+	// escrita na saída é protegida
+	outMu sync.Mutex
+	out   io.Writer
+
+	// force indica que a IA não deve jogar por nenhum dos lados, apenas
+	// aceitar as jogadas recebidas
+	force       bool
+	engineColor chess.Color
+	// post indica se a IA deve exibir o que está pensando durante a busca
+	post bool
+
+	// Controle de tempo configurado pelos comandos level, st e sd
+	depth      int
+	moveTime   time.Duration
+	movesPerTC int
+	increment  time.Duration
+	engineTime time.Duration
+
+	// Busca em andamento. Cada busca recebe um número, de forma que o
+	// resultado de uma busca descartada possa ser ignorado
+	cancel   context.CancelFunc
+	searchID int
+	results  chan xboardSearchResult
+}
+
+// xboardSearchResult é o resultado de uma busca feita em segundo plano
+type xboardSearchResult struct {
+	id     int
+	result *SearchResult
+	err    error
+}
+
+// RunXBoard executa o motor no modo XBoard, lendo os comandos da entrada e
+// escrevendo as respostas na saída informadas, até receber o comando quit
+// ou a entrada terminar
+func RunXBoard(in io.Reader, out io.Writer, engine *Engine) error {
+	s := &xboardSession{
+		engine:      engine,
+		game:        NewGameHistory(chess.NewGame()),
+		out:         out,
+		engineColor: chess.Black,
+		results:     make(chan xboardSearchResult),
+	}
+	defer s.discardSearch()
+
+	// As linhas são lidas em outra goroutine para que os comandos possam ser
+	// tratados enquanto a IA pensa
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		readErr <- scanner.Err()
+		close(lines)
+	}()
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return <-readErr
+			}
+			if quit := s.handle(strings.Fields(line)); quit {
+				return nil
+			}
+		case r := <-s.results:
+			if r.id == s.searchID {
+				s.cancel()
+				s.cancel = nil
+				s.playResult(r)
+			}
+		}
+	}
+}
+
+// send escreve uma linha na saída
+func (s *xboardSession) send(format string, args ...interface{}) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	fmt.Fprintf(s.out, format+"\n", args...)
+}
+
+// handle trata um comando recebido, retornando true quando o comando quit
+// for recebido
+func (s *xboardSession) handle(fields []string) bool {
+	if len(fields) == 0 {
+		return false
+	}
+	cmd, args := fields[0], fields[1:]
+	switch cmd {
+	case "xboard", "accepted", "rejected", "random", "hard", "easy", "computer", "name", "rating", "ics", "variant", "draw", "otim":
+		// Comandos que não alteram o comportamento da IA
+	case "protover":
+		s.send(`feature myname="puc-chess" ping=1 setboard=1 usermove=1 playother=1 colors=0 analyze=0 sigint=0 sigterm=0 reuse=1 done=1`)
+	case "ping":
+		s.send("pong %s", strings.Join(args, " "))
+	case "new":
+		s.discardSearch()
+		s.engine.NewGame()
+		s.game = NewGameHistory(chess.NewGame())
+		s.force = false
+		s.engineColor = chess.Black
+		s.depth = 0
+	case "force":
+		s.discardSearch()
+		s.force = true
+	case "go":
+		s.discardSearch()
+		s.force = false
+		s.engineColor = s.game.Position().Turn()
+		s.startSearch()
+	case "playother":
+		s.discardSearch()
+		s.force = false
+		s.engineColor = s.game.Position().Turn().Other()
+	case "usermove":
+		if len(args) == 0 {
+			s.send("Error (missing move): usermove")
+			return false
+		}
+		s.userMove(args[0])
+	case "?":
+		// Interrompe a busca, que termina jogando a melhor jogada encontrada
+		if s.cancel != nil {
+			s.cancel()
+		}
+	case "level":
+		s.level(args)
+	case "st":
+		if len(args) > 0 {
+			seconds, _ := strconv.Atoi(args[0])
+			s.moveTime = time.Duration(seconds) * time.Second
+		}
+	case "sd":
+		if len(args) > 0 {
+			s.depth, _ = strconv.Atoi(args[0])
+		}
+	case "time":
+		if len(args) > 0 {
+			centiseconds, _ := strconv.Atoi(args[0])
+			s.engineTime = time.Duration(centiseconds) * 10 * time.Millisecond
+		}
+	case "undo":
+		s.discardSearch()
+		s.takeBack(cmd, 1)
+	case "remove":
+		s.discardSearch()
+		s.takeBack(cmd, 2)
+	case "setboard":
+		s.discardSearch()
+		fen, err := chess.FEN(strings.Join(args, " "))
+		if err != nil {
+			s.send("tellusererror Illegal position")
+			return false
+		}
+		s.game = NewGameHistory(chess.NewGame(fen))
+	case "result":
+		s.discardSearch()
+		s.force = true
+	case "post":
+		s.post = true
+	case "nopost":
+		s.post = false
+	case "quit":
+		return true
+	default:
+		s.send("Error (unknown command): %s", cmd)
+	}
+	return false
+}
+
+// level trata o comando "level MPS BASE INC", em que MPS é o número de
+// jogadas por controle de tempo, BASE é o tempo em minutos, ou minutos e
+// segundos no formato "m:ss", e INC é o incremento em segundos
+func (s *xboardSession) level(args []string) {
+	if len(args) < 3 {
+		s.send("Error (missing arguments): level")
+		return
+	}
+	s.movesPerTC, _ = strconv.Atoi(args[0])
+	// O tempo base é usado até que a interface informe o relógio com o
+	// comando time
+	minutes, seconds, _ := strings.Cut(args[1], ":")
+	m, _ := strconv.Atoi(minutes)
+	sec, _ := strconv.Atoi(seconds)
+	s.engineTime = time.Duration(m)*time.Minute + time.Duration(sec)*time.Second
+	increment, _ := strconv.ParseFloat(args[2], 64)
+	s.increment = time.Duration(increment * float64(time.Second))
+	s.moveTime = 0
+}
+
+// userMove faz a jogada recebida da interface, descartando a busca em
+// andamento, e, se for a vez da IA, começa a pensar na resposta
+func (s *xboardSession) userMove(moveStr string) {
+	// A busca em andamento lê a mesma partida, então ela é descartada antes
+	// que a jogada altere o tabuleiro
+	s.discardSearch()
+	if s.game.Outcome() != chess.NoOutcome {
+		s.send("Illegal move: %s", moveStr)
+		return
+	}
+	if err := MoveUCI(s.game.Game, moveStr); err != nil {
+		s.send("Illegal move: %s", moveStr)
+		return
+	}
+	if s.reportOutcome() {
+		return
+	}
+	if !s.force && s.game.Position().Turn() == s.engineColor {
+		s.startSearch()
+	}
+}
+
+// startSearch começa a busca pela jogada da IA em segundo plano
+func (s *xboardSession) startSearch() {
+	if s.game.Outcome() != chess.NoOutcome {
+		return
+	}
+	limits := SearchLimits{Depth: s.depth, MoveTime: s.moveTime}
+	if limits.MoveTime == 0 && s.engineTime > 0 {
+		movesToGo := 0
+		if s.movesPerTC > 0 {
+			played := len(s.game.Moves()) / 2
+			movesToGo = s.movesPerTC - played%s.movesPerTC
+		}
+		limits.MoveTime = AllocateMoveTime(s.engineTime, s.increment, movesToGo)
+	}
+
+	s.engine.OnIteration = nil
+	if s.post {
+		evaluator := s.engine.Evaluator
+		s.engine.OnIteration = func(r *SearchResult) {
+			s.send("%d %d %d %d %s", r.Depth, xboardScore(evaluator, r.Score), r.Time.Milliseconds()/10, r.Nodes, FormatMoves(r.PV))
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.searchID++
+	id, game, results := s.searchID, s.game.Game, s.results
+	go func() {
+		result, err := s.engine.IterativeDeepening(ctx, game, limits)
+		results <- xboardSearchResult{id: id, result: result, err: err}
+	}()
+}
+
+// xboardScore converte a avaliação para o formato esperado pela interface:
+// centipeões, ou 100000+N para um mate em N jogadas e -100000-N para quem
+// leva mate em N jogadas
+func xboardScore(evaluator Evaluator, score int) int {
+	if score >= maxMateScore {
+		return 100000 + (mateScore-score+1)/2
+	} else if score <= -maxMateScore {
+		return -100000 - (mateScore+score)/2
+	}
+	return Centipawns(evaluator, score)
+}
+
+// discardSearch interrompe a busca em andamento, se houver, ignorando o seu
+// resultado
+func (s *xboardSession) discardSearch() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	// Aguarda a busca terminar, já que ela usa o estado do motor
+	for r := range s.results {
+		if r.id == s.searchID {
+			break
+		}
+	}
+	s.cancel = nil
+}
+
+// playResult faz a jogada encontrada pela busca e a envia para a interface
+func (s *xboardSession) playResult(r xboardSearchResult) {
+	if r.err != nil || r.result == nil {
+		s.send("Error (search failed): %v", r.err)
+		return
+	}
+	if err := s.game.Move(r.result.Move); err != nil {
+		s.send("Error (illegal engine move): %s", r.result.Move)
+		return
+	}
+	s.send("move %s", r.result.Move)
+	s.reportOutcome()
+}
+
+// takeBack desfaz as últimas jogadas da partida, informando o comando que
+// pediu para desfazê-las em caso de erro
+func (s *xboardSession) takeBack(cmd string, n int) {
+	if err := s.game.TakeBack(n); err != nil {
+		s.send("Error (%s): %s", err, cmd)
+	}
+}
+
+// reportOutcome informa a interface quando a partida terminar, retornando
+// true nesse caso
+func (s *xboardSession) reportOutcome() bool {
+	if s.game.Outcome() == chess.NoOutcome {
+		return false
+	}
+	s.send("%s {%s}", s.game.Outcome(), describeOutcome(s.game.Game))
+	return true
+}
+
+// describeOutcome descreve como a partida terminou
+func describeOutcome(game *chess.Game) string {
+	if endedOnTime(game) {
+		switch game.Outcome() {
+		case chess.WhiteWon:
+			return "Black loses on time"
+		case chess.BlackWon:
+			return "White loses on time"
+		}
+		return "Time forfeit against insufficient material"
+	}
+	switch game.Method() {
+	case chess.Checkmate:
+		if game.Outcome() == chess.WhiteWon {
+			return "White mates"
+		}
+		return "Black mates"
+	case chess.Resignation:
+		if game.Outcome() == chess.WhiteWon {
+			return "Black resigns"
+		}
+		return "White resigns"
+	case chess.Stalemate:
+		return "Stalemate"
+	case chess.InsufficientMaterial:
+		return "Insufficient material"
+	case chess.FivefoldRepetition, chess.ThreefoldRepetition:
+		return "Draw by repetition"
+	case chess.SeventyFiveMoveRule, chess.FiftyMoveRule:
+		return "Draw by fifty move rule"
+	case chess.DrawOffer:
+		return "Draw by agreement"
+	}
+	return game.Method().String()
+}
+
+// describeMethod retorna a forma de término da partida, considerando a queda
+// da bandeira, que o chess.Game registra como abandono ou empate
+func describeMethod(game *chess.Game) string {
+	if endedOnTime(game) {
+		return "TimeForfeit"
+	}
+	return game.Method().String()
+}
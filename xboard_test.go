@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+func TestXBoardScore(t *testing.T) {
+	tests := []struct {
+		evaluator Evaluator
+		score     int
+		want      int
+	}{
+		{MaterialEvaluator{}, 10, 100},
+		{MaterialEvaluator{}, -35, -350},
+		{NewPositionalEvaluator(), 82, 82},
+		{MaterialEvaluator{}, mateScore - 1, 100001},
+		{MaterialEvaluator{}, mateScore - 3, 100002},
+		{MaterialEvaluator{}, -mateScore + 2, -100001},
+		{MaterialEvaluator{}, -mateScore + 4, -100002},
+	}
+	for _, tt := range tests {
+		if got := xboardScore(tt.evaluator, tt.score); got != tt.want {
+			t.Errorf("xboardScore(%T, %d) = %d, want %d", tt.evaluator, tt.score, got, tt.want)
+		}
+	}
+}
+
+// xboardClient conversa com uma sessão XBoard pela entrada e pela saída,
+// como uma interface gráfica faria
+type xboardClient struct {
+	t     *testing.T
+	in    *io.PipeWriter
+	lines chan string
+	done  chan error
+	pings int
+}
+
+// startXBoard inicia uma sessão XBoard em outra goroutine
+func startXBoard(t *testing.T) *xboardClient {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	c := &xboardClient{
+		t:     t,
+		in:    inWriter,
+		lines: make(chan string, 100),
+		done:  make(chan error, 1),
+	}
+	go func() {
+		err := RunXBoard(inReader, outWriter, NewEngine(1))
+		outWriter.Close()
+		c.done <- err
+	}()
+	go func() {
+		scanner := bufio.NewScanner(outReader)
+		for scanner.Scan() {
+			c.lines <- scanner.Text()
+		}
+		close(c.lines)
+	}()
+	return c
+}
+
+// send envia um comando para a sessão
+func (c *xboardClient) send(cmd string) {
+	c.t.Helper()
+	if _, err := fmt.Fprintln(c.in, cmd); err != nil {
+		c.t.Fatalf("sending %q failed: %v", cmd, err)
+	}
+}
+
+// expect lê a saída até encontrar uma linha que comece com o prefixo
+// informado, retornando essa linha e as que vieram antes dela
+func (c *xboardClient) expect(prefix string) (string, []string) {
+	c.t.Helper()
+	var before []string
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				c.t.Fatalf("the session ended while waiting for %q, after %q", prefix, before)
+			}
+			if strings.HasPrefix(line, prefix) {
+				return line, before
+			}
+			before = append(before, line)
+		case <-timeout:
+			c.t.Fatalf("timed out waiting for %q, after %q", prefix, before)
+		}
+	}
+}
+
+// sync usa o comando ping para ler toda a saída produzida pelos comandos já
+// enviados, falhando se ela contiver alguma linha não esperada
+func (c *xboardClient) sync(allowed ...string) {
+	c.t.Helper()
+	c.pings++
+	pong := fmt.Sprintf("pong %d", c.pings)
+	c.send(fmt.Sprintf("ping %d", c.pings))
+	_, before := c.expect(pong)
+	for _, line := range before {
+		ok := false
+		for _, prefix := range allowed {
+			ok = ok || strings.HasPrefix(line, prefix)
+		}
+		if !ok {
+			c.t.Errorf("unexpected output %q", line)
+		}
+	}
+}
+
+// engineMove espera a jogada da IA e a faz na partida informada, que precisa
+// acompanhar a partida da sessão
+func (c *xboardClient) engineMove(game *chess.Game) string {
+	c.t.Helper()
+	line, _ := c.expect("move ")
+	move := strings.TrimPrefix(line, "move ")
+	if err := MoveUCI(game, move); err != nil {
+		c.t.Fatalf("the engine played %s, which is not legal: %v", move, err)
+	}
+	return move
+}
+
+// quit encerra a sessão
+func (c *xboardClient) quit() {
+	c.t.Helper()
+	c.send("quit")
+	select {
+	case err := <-c.done:
+		if err != nil {
+			c.t.Errorf("RunXBoard returned an error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		c.t.Fatal("RunXBoard did not return after quit")
+	}
+}
+
+func TestRunXBoard(t *testing.T) {
+	c := startXBoard(t)
+	c.send("xboard")
+	c.send("protover 2")
+	c.expect("feature ")
+
+	// A IA joga com as pretas depois da jogada das brancas, exibindo o que
+	// está pensando
+	c.send("new")
+	c.send("sd 2")
+	c.send("post")
+	c.send("usermove e2e4")
+	game := gameAfter(t, "e4")
+	_, thinking := c.expect("move ")
+	if len(thinking) != 2 || !strings.HasPrefix(thinking[1], "2 ") || len(strings.Fields(thinking[1])) < 5 {
+		t.Errorf("the post output should have one line per depth, got %q", thinking)
+	}
+	c.send("nopost")
+	c.sync()
+
+	// O remove desfaz a jogada da IA e a do usuário, e em force a IA não
+	// responde às jogadas
+	c.send("force")
+	c.send("remove")
+	c.send("usermove d2d4")
+	c.send("usermove d7d5")
+	c.sync()
+	// O undo desfaz apenas d7d5, então as pretas podem jogar de novo
+	c.send("undo")
+	c.send("usermove g8f6")
+	c.sync()
+
+	// Com go a IA passa a jogar com as brancas
+	game = gameAfter(t, "d4", "Nf6")
+	c.send("go")
+	c.engineMove(game)
+	c.send("usermove " + game.ValidMoves()[0].String())
+	game.Move(game.ValidMoves()[0])
+	c.engineMove(game)
+	c.sync()
+
+	// Sem jogadas para desfazer, os dois comandos falham com os seus nomes
+	c.send("new")
+	for _, cmd := range []string{"undo", "remove"} {
+		c.send(cmd)
+		if line, _ := c.expect("Error ("); !strings.HasSuffix(line, ": "+cmd) {
+			t.Errorf("%s failed with %q", cmd, line)
+		}
+	}
+	c.sync()
+
+	// Depois do setboard a IA encontra o mate e a partida termina
+	c.send("setboard 6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1")
+	c.send("go")
+	if line, _ := c.expect("move "); line != "move a1a8" {
+		t.Errorf("got %q, want the mate a1a8", line)
+	}
+	if line, _ := c.expect("1-0"); line != "1-0 {White mates}" {
+		t.Errorf("got %q, want the result of the game", line)
+	}
+	c.send("setboard not a position")
+	c.expect("tellusererror Illegal position")
+	c.sync()
+	c.quit()
+}
+
+func TestXBoardMoveNow(t *testing.T) {
+	// Sem limite de profundidade a busca só termina com o ?, que precisa
+	// jogar a melhor jogada encontrada
+	c := startXBoard(t)
+	c.send("new")
+	c.send("st 60")
+	c.send("usermove e2e4")
+	time.Sleep(50 * time.Millisecond)
+	c.send("?")
+	c.engineMove(gameAfter(t, "e4"))
+	c.sync()
+	c.quit()
+}
+
+func TestXBoardDiscardsSearch(t *testing.T) {
+	// Os comandos que mudam a partida descartam a busca em andamento, cuja
+	// jogada não pode ser enviada depois. Isso inclui uma jogada do usuário
+	// pelo lado da IA enquanto ela pensa
+	c := startXBoard(t)
+	for _, cmd := range []string{"new", "undo", "force", "setboard rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "usermove e7e5"} {
+		c.send("new")
+		c.send("st 60")
+		c.send("usermove e2e4")
+		time.Sleep(20 * time.Millisecond)
+		c.send(cmd)
+		// O ? não pode encontrar nenhuma busca para interromper. Se
+		// encontrasse, a jogada dela chegaria logo depois
+		c.send("?")
+		time.Sleep(100 * time.Millisecond)
+		c.sync()
+	}
+
+	// A sessão continua funcionando depois dos descartes
+	c.send("new")
+	c.send("st 0")
+	c.send("sd 1")
+	c.send("usermove d2d4")
+	c.engineMove(gameAfter(t, "d4"))
+	c.sync()
+	c.quit()
+}